@@ -0,0 +1,29 @@
+package cache
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+)
+
+// TxStep часть транзакции, которую одно хранилище добавляет в транзакцию другого хранилища,
+// чтобы изменения разных сущностей сохранились вместе или не сохранились совсем
+type TxStep interface {
+	Keys() []string                                  // ключи, которые отслеживаются через WATCH вместе с ключами транзакции
+	Check(ctx context.Context, tx *redis.Tx) error   // проверить данные после WATCH, ошибка отменяет транзакцию
+	Queue(ctx context.Context, pipe redis.Pipeliner) // добавить команды шага в MULTI
+}
+
+// QueueStep шаг транзакции, которому нечего проверять: он только добавляет свои команды в MULTI
+type QueueStep func(ctx context.Context, pipe redis.Pipeliner)
+
+func (f QueueStep) Keys() []string {
+	return nil
+}
+
+func (f QueueStep) Check(ctx context.Context, tx *redis.Tx) error {
+	return nil
+}
+
+func (f QueueStep) Queue(ctx context.Context, pipe redis.Pipeliner) {
+	f(ctx, pipe)
+}
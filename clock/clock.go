@@ -0,0 +1,88 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock источник текущего времени, позволяет подменять время в тестах и при воспроизведении симуляции
+type Clock interface {
+	Now() time.Time
+}
+
+// Real системные часы
+type Real struct{}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Offset часы, которые начинают отсчет с заданного момента и идут с обычной скоростью,
+// например для воспроизведения симуляции с фиксированного времени
+type Offset struct {
+	start   time.Time
+	started time.Time
+}
+
+func NewOffset(start time.Time) *Offset {
+	return &Offset{start: start, started: time.Now()}
+}
+
+func (o *Offset) Now() time.Time {
+	return o.start.Add(time.Since(o.started))
+}
+
+// Stepped часы для воспроизведения симуляции: каждое чтение сдвигает время на step,
+// поэтому время событий зависит только от их порядка, а не от скорости выполнения
+type Stepped struct {
+	mu   sync.Mutex
+	now  time.Time
+	step time.Duration
+}
+
+func NewStepped(start time.Time, step time.Duration) *Stepped {
+	return &Stepped{now: start, step: step}
+}
+
+func (s *Stepped) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now
+	s.now = s.now.Add(s.step)
+
+	return now
+}
+
+// Manual часы, которые меняются только вручную
+type Manual struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewManual(now time.Time) *Manual {
+	return &Manual{now: now}
+}
+
+func (m *Manual) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.now
+}
+
+// Set устанавливает текущее время
+func (m *Manual) Set(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = now
+}
+
+// Add сдвигает текущее время на d
+func (m *Manual) Add(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = m.now.Add(d)
+}
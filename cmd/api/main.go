@@ -6,6 +6,7 @@ import (
 	"github.com/joho/godotenv"
 	"log"
 	"os"
+	_ "time/tzdata" // часовые пояса для расписаний зон, в alpine образе нет tzdata
 )
 
 func main() {
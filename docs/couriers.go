@@ -0,0 +1,139 @@
+package docs
+
+import (
+	"github.com/GoGerman/geo-task/module/courier/controller"
+	"github.com/GoGerman/geo-task/module/courier/models"
+)
+
+// swagger:route GET /api/couriers couriers ListCouriers
+// List active couriers
+// Responses:
+//   200: ListCouriersRes200
+
+// swagger:response ListCouriersRes200
+type CouriersResponse struct {
+	// in:body
+	Body []models.Courier
+}
+
+// swagger:route POST /api/couriers couriers CreateCourier
+// Create offline courier at default location, vehicle sets how many orders the courier carries
+// Responses:
+//   201: CourierRes200
+//   400: ErrorRes
+
+// swagger:parameters CreateCourier
+type CreateCourierRequest struct {
+	// in:body
+	Body controller.CreateRequest
+}
+
+// swagger:route GET /api/couriers/nearby couriers NearbyCouriers
+// List couriers nearest to the point, sorted by distance
+// Responses:
+//   200: ListCouriersRes200
+//   400: ErrorRes
+
+// swagger:parameters NearbyCouriers
+type NearbyCouriersParams struct {
+	// in:query
+	// required: true
+	Lat float64 `json:"lat"`
+	// in:query
+	// required: true
+	Lng float64 `json:"lng"`
+	// радиус поиска в метрах, без него ищутся ближайшие курьеры на любом расстоянии
+	// in:query
+	Radius float64 `json:"radius"`
+	// количество курьеров, по умолчанию 10
+	// in:query
+	Limit int `json:"limit"`
+}
+
+// swagger:route GET /api/couriers/{id} couriers GetCourier
+// Get courier by id
+// Responses:
+//   200: CourierRes200
+//   404: ErrorRes
+
+// swagger:route DELETE /api/couriers/{id} couriers DeleteCourier
+// Delete courier
+// Responses:
+//   204: NoContentRes
+//   404: ErrorRes
+
+// swagger:route POST /api/couriers/{id}/state couriers SetCourierState
+// Take courier online (idle) or offline, order states change only with pickups and deliveries
+// Responses:
+//   200: CourierRes200
+//   400: ErrorRes
+//   404: ErrorRes
+//   409: ErrorRes
+
+// swagger:parameters SetCourierState
+type CourierStateRequest struct {
+	// in:path
+	// required: true
+	ID string `json:"id"`
+	// in:body
+	Body struct {
+		// offline или idle
+		// required: true
+		State string `json:"state"`
+	}
+}
+
+// swagger:route GET /api/couriers/{id}/states couriers GetCourierStates
+// Get courier state transitions history
+// Responses:
+//   200: CourierStatesRes200
+
+// swagger:response CourierStatesRes200
+type CourierStatesResponse struct {
+	// in:body
+	Body []models.StateTransition
+}
+
+// swagger:parameters GetCourier DeleteCourier GetCourierStates
+type CourierIDParam struct {
+	// in:path
+	// required: true
+	ID string `json:"id"`
+}
+
+// swagger:response CourierRes200
+type CourierByIDResponse struct {
+	// in:body
+	Body models.Courier
+}
+
+// swagger:route GET /api/couriers/{id}/track couriers GetCourierTrack
+// Get courier location history as GeoJSON LineString or GPX
+// Produces:
+// - application/geo+json
+// - application/gpx+xml
+// Responses:
+//   200: CourierTrackRes200
+//   400: ErrorRes
+
+// swagger:parameters GetCourierTrack
+type CourierTrackParams struct {
+	// in:path
+	// required: true
+	ID string `json:"id"`
+	// начало периода в формате RFC3339
+	// in:query
+	From string `json:"from"`
+	// конец периода в формате RFC3339
+	// in:query
+	To string `json:"to"`
+	// geojson или gpx, по умолчанию geojson
+	// in:query
+	Format string `json:"format"`
+}
+
+// swagger:response CourierTrackRes200
+type CourierTrackResponse struct {
+	// in:body
+	Body interface{}
+}
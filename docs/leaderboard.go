@@ -0,0 +1,28 @@
+package docs
+
+import "github.com/GoGerman/geo-task/module/leaderboard/models"
+
+// swagger:route GET /api/leaderboard leaderboard GetLeaderboard
+// Get top couriers for the period and the rank of the requesting courier
+// Responses:
+//   200: LeaderboardRes200
+//   400: ErrorRes
+
+// swagger:parameters GetLeaderboard
+type LeaderboardParams struct {
+	// day, week или all, по умолчанию all
+	// in:query
+	Period string `json:"period"`
+	// количество курьеров, по умолчанию 10, не больше 100
+	// in:query
+	Limit int `json:"limit"`
+	// курьер, место которого нужно вернуть
+	// in:query
+	CourierID string `json:"courier_id"`
+}
+
+// swagger:response LeaderboardRes200
+type LeaderboardResponse struct {
+	// in:body
+	Body models.Leaderboard
+}
@@ -0,0 +1,67 @@
+package docs
+
+import (
+	"github.com/GoGerman/geo-task/module/ledger/controller"
+	"github.com/GoGerman/geo-task/module/ledger/models"
+)
+
+// swagger:route GET /api/couriers/{id}/ledger ledger ListLedger
+// List courier ledger entries from newest to oldest, amounts in minor units (kopecks)
+// Responses:
+//   200: LedgerPageRes200
+//   400: ErrorRes
+//   404: ErrorRes
+
+// swagger:route POST /api/couriers/{id}/ledger ledger CreateLedgerEntry
+// Record bonus, penalty or adjustment, payouts are recorded on delivery
+// Responses:
+//   201: LedgerEntryRes200
+//   400: ErrorRes
+//   404: ErrorRes
+
+// swagger:route GET /api/couriers/{id}/balance ledger GetBalance
+// Get courier balance in minor units (kopecks)
+// Responses:
+//   200: BalanceRes200
+//   404: ErrorRes
+
+// swagger:parameters ListLedger CreateLedgerEntry GetBalance
+type CourierLedgerParams struct {
+	// in:path
+	// required: true
+	ID string `json:"id"`
+}
+
+// swagger:parameters ListLedger
+type ListLedgerParams struct {
+	// количество записей, по умолчанию 20, не больше 100
+	// in:query
+	Limit int `json:"limit"`
+	// курсор next с предыдущей страницы
+	// in:query
+	Before string `json:"before"`
+}
+
+// swagger:parameters CreateLedgerEntry
+type CreateLedgerEntryRequest struct {
+	// in:body
+	Body controller.EntryRequest
+}
+
+// swagger:response LedgerPageRes200
+type LedgerPageResponse struct {
+	// in:body
+	Body models.Page
+}
+
+// swagger:response LedgerEntryRes200
+type LedgerEntryResponse struct {
+	// in:body
+	Body models.Entry
+}
+
+// swagger:response BalanceRes200
+type BalanceResponse struct {
+	// in:body
+	Body models.Balance
+}
@@ -0,0 +1,74 @@
+package docs
+
+import (
+	"github.com/GoGerman/geo-task/module/shift/controller"
+	"github.com/GoGerman/geo-task/module/shift/models"
+)
+
+// swagger:route GET /api/couriers/{id}/shifts shifts ListShifts
+// List courier shifts with distance, deliveries and earnings per shift
+// Responses:
+//   200: ListShiftsRes200
+
+// swagger:route POST /api/couriers/{id}/shifts shifts CreateShift
+// Schedule courier shift with breaks, shifts of one courier must not overlap
+// Responses:
+//   201: ShiftRes200
+//   400: ErrorRes
+//   404: ErrorRes
+//   409: ErrorRes
+
+// swagger:route GET /api/couriers/{id}/shifts/{shift_id} shifts GetShift
+// Get courier shift with its report
+// Responses:
+//   200: ShiftRes200
+//   404: ErrorRes
+
+// swagger:route POST /api/couriers/{id}/shifts/{shift_id}/start shifts StartShift
+// Start shift, courier goes online
+// Responses:
+//   200: ShiftRes200
+//   404: ErrorRes
+//   409: ErrorRes
+
+// swagger:route POST /api/couriers/{id}/shifts/{shift_id}/end shifts EndShift
+// End shift, courier goes offline
+// Responses:
+//   200: ShiftRes200
+//   404: ErrorRes
+//   409: ErrorRes
+
+// swagger:parameters ListShifts CreateShift
+type CourierShiftsParams struct {
+	// in:path
+	// required: true
+	ID string `json:"id"`
+}
+
+// swagger:parameters CreateShift
+type CreateShiftRequest struct {
+	// in:body
+	Body controller.ShiftRequest
+}
+
+// swagger:parameters GetShift StartShift EndShift
+type ShiftIDParams struct {
+	// in:path
+	// required: true
+	ID string `json:"id"`
+	// in:path
+	// required: true
+	ShiftID string `json:"shift_id"`
+}
+
+// swagger:response ListShiftsRes200
+type ShiftsResponse struct {
+	// in:body
+	Body []models.Shift
+}
+
+// swagger:response ShiftRes200
+type ShiftResponse struct {
+	// in:body
+	Body models.Shift
+}
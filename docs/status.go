@@ -8,6 +8,15 @@ import "github.com/GoGerman/geo-task/module/courierfacade/models"
 // Get courier status
 // Responses:
 //   200: GetStatusRes200
+//   400: ErrorRes
+//   404: ErrorRes
+
+// swagger:parameters GetStatus
+type GetStatusParams struct {
+	// in:query
+	// required: true
+	CourierID string `json:"courier_id"`
+}
 
 // swagger:response GetStatusRes200
 type CourierResponse struct {
@@ -0,0 +1,93 @@
+package docs
+
+import "github.com/GoGerman/geo-task/module/zone/models"
+
+// swagger:route GET /api/zones zones ListZones
+// List zones
+// Responses:
+//   200: ListZonesRes200
+
+// swagger:response ListZonesRes200
+type ZonesResponse struct {
+	// in:body
+	Body []models.Zone
+}
+
+// swagger:route GET /api/zones/{id} zones GetZone
+// Get zone by id
+// Responses:
+//   200: ZoneRes200
+//   404: ErrorRes
+
+// swagger:route POST /api/zones zones CreateZone
+// Create zone
+// Responses:
+//   201: ZoneRes200
+//   400: ErrorRes
+//   409: ErrorRes
+
+// swagger:route PUT /api/zones/{id} zones UpdateZone
+// Update zone
+// Responses:
+//   200: ZoneRes200
+//   400: ErrorRes
+//   404: ErrorRes
+
+// swagger:route DELETE /api/zones/{id} zones DeleteZone
+// Delete zone
+// Responses:
+//   204: NoContentRes
+//   400: ErrorRes
+//   404: ErrorRes
+
+// swagger:parameters GetZone UpdateZone DeleteZone
+type ZoneIDParam struct {
+	// in:path
+	// required: true
+	ID string `json:"id"`
+}
+
+// swagger:parameters CreateZone UpdateZone
+type ZoneRequest struct {
+	// in:body
+	Body models.Zone
+}
+
+// swagger:response ZoneRes200
+type ZoneResponse struct {
+	// in:body
+	Body models.Zone
+}
+
+// swagger:response NoContentRes
+type NoContentResponse struct{}
+
+// swagger:response ErrorRes
+type ErrorResponse struct {
+	// in:body
+	Body struct {
+		Error string `json:"error"`
+	}
+}
+
+// swagger:route GET /api/zones.geojson zones GetZonesGeoJSON
+// Get zones as GeoJSON FeatureCollection
+// Produces:
+//   - application/geo+json
+// Responses:
+//   200: ZonesGeoJSONRes200
+//   304: NoContentRes
+
+// swagger:parameters GetZonesGeoJSON
+type ZonesGeoJSONParams struct {
+	// in:header
+	IfNoneMatch string `json:"If-None-Match"`
+}
+
+// swagger:response ZonesGeoJSONRes200
+type ZonesGeoJSONResponse struct {
+	// ETag набора зон
+	ETag string
+	// in:body
+	Body map[string]interface{}
+}
@@ -0,0 +1,124 @@
+package geo
+
+import (
+	"fmt"
+	"github.com/GoGerman/geo-task/clock"
+	"math"
+)
+
+// известные атрибуты зон для расчета стоимости доставки и ограничения количества заказов
+const (
+	AttributePriceMultiplier  = "price_multiplier"   // множитель стоимости доставки
+	AttributeMinDeliveryPrice = "min_delivery_price" // минимальная стоимость доставки
+	AttributeMaxDeliveryPrice = "max_delivery_price" // максимальная стоимость доставки
+	AttributeMaxOrders        = "max_orders"         // максимальное количество доступных заказов в зоне
+)
+
+// Attributes числовые атрибуты зоны, например параметры ценообразования района
+type Attributes map[string]float64
+
+// Value возвращает значение атрибута или def, если атрибут не задан
+func (a Attributes) Value(key string, def float64) float64 {
+	if v, ok := a[key]; ok {
+		return v
+	}
+
+	return def
+}
+
+// Validate проверяет значения известных атрибутов, неизвестные атрибуты не проверяются
+func (a Attributes) Validate() error {
+	if v, ok := a[AttributePriceMultiplier]; ok && !(v > 0) {
+		return fmt.Errorf("attribute %q must be positive", AttributePriceMultiplier)
+	}
+
+	for _, key := range []string{AttributeMinDeliveryPrice, AttributeMaxDeliveryPrice} {
+		if v, ok := a[key]; ok && !(v >= 0) {
+			return fmt.Errorf("attribute %q must not be negative", key)
+		}
+	}
+
+	minPrice, hasMin := a[AttributeMinDeliveryPrice]
+	maxPrice, hasMax := a[AttributeMaxDeliveryPrice]
+	if hasMin && hasMax && minPrice > maxPrice {
+		return fmt.Errorf("attribute %q must not exceed %q", AttributeMinDeliveryPrice, AttributeMaxDeliveryPrice)
+	}
+
+	if v, ok := a[AttributeMaxOrders]; ok && (v < 0 || v != math.Trunc(v)) {
+		return fmt.Errorf("attribute %q must be a non-negative integer", AttributeMaxOrders)
+	}
+
+	return nil
+}
+
+// AttributedZone зона, атрибуты которой применяются к точкам внутри нее
+type AttributedZone struct {
+	Zone       PolygonChecker
+	Attributes Attributes
+}
+
+// buildAttributedZones строит зоны с атрибутами в порядке описаний.
+// Разрешенные зоны здесь не объединяются, чтобы у каждого района остались свои атрибуты
+func buildAttributedZones(specs []ZoneSpec, clk clock.Clock) ([]AttributedZone, error) {
+	var res []AttributedZone
+
+	for i := range specs {
+		if len(specs[i].Attributes) == 0 {
+			continue
+		}
+
+		err := specs[i].Attributes.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("zone %q: %w", specs[i].ID, err)
+		}
+
+		parts, err := specs[i].Geometry.parts()
+		if err != nil {
+			return nil, fmt.Errorf("geojson: zone %q: %w", specs[i].ID, err)
+		}
+
+		parts, err = ValidateParts(specs[i].ID, parts)
+		if err != nil {
+			return nil, err
+		}
+
+		var zone PolygonChecker = NewMultiPolygon(specs[i].ID, parts, specs[i].Allowed)
+		if specs[i].Schedule != nil {
+			zone, err = NewScheduledZone(zone, specs[i].Schedule, clk)
+			if err != nil {
+				return nil, fmt.Errorf("zone %q: invalid schedule: %w", specs[i].ID, err)
+			}
+		}
+
+		res = append(res, AttributedZone{Zone: zone, Attributes: specs[i].Attributes})
+	}
+
+	return res, nil
+}
+
+// attributedZonesAt возвращает зоны с атрибутами, содержащие точку, в порядке описаний
+func attributedZonesAt(point Point, zones []AttributedZone) []AttributedZone {
+	var res []AttributedZone
+
+	for i := range zones {
+		if zones[i].Zone.Bounds().Contains(point) && zones[i].Zone.Contains(point) {
+			res = append(res, zones[i])
+		}
+	}
+
+	return res
+}
+
+// attributesAt собирает атрибуты всех зон, содержащих точку.
+// Если атрибут задан в нескольких зонах, берется значение из зоны, описанной позже
+func attributesAt(point Point, zones []AttributedZone) Attributes {
+	res := Attributes{}
+
+	for _, zone := range attributedZonesAt(point, zones) {
+		for k, v := range zone.Attributes {
+			res[k] = v
+		}
+	}
+
+	return res
+}
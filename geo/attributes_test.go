@@ -0,0 +1,98 @@
+package geo
+
+import (
+	"github.com/GoGerman/geo-task/clock"
+	"testing"
+)
+
+func TestAttributesValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes Attributes
+		wantErr    bool
+	}{
+		{name: "empty", attributes: Attributes{}},
+		{name: "unknown attribute", attributes: Attributes{"rating": -1}},
+		{name: "pricing", attributes: Attributes{AttributePriceMultiplier: 1.5, AttributeMinDeliveryPrice: 100, AttributeMaxDeliveryPrice: 300}},
+		{name: "min equals max", attributes: Attributes{AttributeMinDeliveryPrice: 200, AttributeMaxDeliveryPrice: 200}},
+		{name: "only min", attributes: Attributes{AttributeMinDeliveryPrice: 900}},
+		{name: "zero max orders", attributes: Attributes{AttributeMaxOrders: 0}},
+		{name: "min greater than max", attributes: Attributes{AttributeMinDeliveryPrice: 300, AttributeMaxDeliveryPrice: 100}, wantErr: true},
+		{name: "negative min", attributes: Attributes{AttributeMinDeliveryPrice: -1}, wantErr: true},
+		{name: "negative max", attributes: Attributes{AttributeMaxDeliveryPrice: -1}, wantErr: true},
+		{name: "zero multiplier", attributes: Attributes{AttributePriceMultiplier: 0}, wantErr: true},
+		{name: "negative max orders", attributes: Attributes{AttributeMaxOrders: -1}, wantErr: true},
+		{name: "fractional max orders", attributes: Attributes{AttributeMaxOrders: 2.5}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.attributes.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewZoneSetRejectsInvalidAttributes(t *testing.T) {
+	specs := []ZoneSpec{
+		{ID: "city", Allowed: true, Geometry: Geometry{Type: GeometryPolygon, Coordinates: []byte(`[[[0,0],[10,0],[10,10],[0,10],[0,0]]]`)}},
+		{ID: "center", Overlay: true, Attributes: Attributes{AttributeMinDeliveryPrice: 500, AttributeMaxDeliveryPrice: 100},
+			Geometry: Geometry{Type: GeometryPolygon, Coordinates: []byte(`[[[2,2],[4,2],[4,4],[2,4],[2,2]]]`)}},
+	}
+
+	_, err := NewZoneSet(specs, clock.Real{})
+	if err == nil {
+		t.Fatal("NewZoneSet() error = nil, want invalid attributes error")
+	}
+}
+
+func TestZoneSetAttributesAt(t *testing.T) {
+	specs := []ZoneSpec{
+		{ID: "city", Allowed: true, Geometry: Geometry{Type: GeometryPolygon, Coordinates: []byte(`[[[0,0],[10,0],[10,10],[0,10],[0,0]]]`)}},
+		{ID: "district", Overlay: true, Attributes: Attributes{AttributePriceMultiplier: 2, AttributeMaxOrders: 5},
+			Geometry: Geometry{Type: GeometryPolygon, Coordinates: []byte(`[[[0,0],[5,0],[5,10],[0,10],[0,0]]]`)}},
+		{ID: "center", Overlay: true, Attributes: Attributes{AttributePriceMultiplier: 3},
+			Geometry: Geometry{Type: GeometryPolygon, Coordinates: []byte(`[[[2,2],[4,2],[4,4],[2,4],[2,2]]]`)}},
+	}
+
+	set, err := NewZoneSet(specs, clock.Real{})
+	if err != nil {
+		t.Fatalf("NewZoneSet() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		point      Point
+		wantZones  []string
+		wantFactor float64
+		wantLimit  float64
+	}{
+		{name: "outside districts", point: Point{Lat: 7, Lng: 7}, wantFactor: 1, wantLimit: -1},
+		{name: "district", point: Point{Lat: 1, Lng: 1}, wantZones: []string{"district"}, wantFactor: 2, wantLimit: 5},
+		{name: "later zone overrides", point: Point{Lat: 3, Lng: 3}, wantZones: []string{"district", "center"}, wantFactor: 3, wantLimit: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zones := set.AttributedZonesAt(tt.point)
+			if len(zones) != len(tt.wantZones) {
+				t.Fatalf("AttributedZonesAt() = %d zones, want %v", len(zones), tt.wantZones)
+			}
+			for i := range zones {
+				if zones[i].Zone.ID() != tt.wantZones[i] {
+					t.Errorf("AttributedZonesAt()[%d] = %q, want %q", i, zones[i].Zone.ID(), tt.wantZones[i])
+				}
+			}
+
+			attributes := set.AttributesAt(tt.point)
+			if got := attributes.Value(AttributePriceMultiplier, 1); got != tt.wantFactor {
+				t.Errorf("price multiplier = %v, want %v", got, tt.wantFactor)
+			}
+			if got := attributes.Value(AttributeMaxOrders, -1); got != tt.wantLimit {
+				t.Errorf("max orders = %v, want %v", got, tt.wantLimit)
+			}
+		})
+	}
+}
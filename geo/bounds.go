@@ -0,0 +1,79 @@
+package geo
+
+import (
+	"errors"
+	"github.com/GoGerman/geo-task/random"
+)
+
+// maxRandomPointAttempts ограничение количества попыток при генерации случайной точки
+const maxRandomPointAttempts = 1000
+
+var ErrRandomPointNotFound = errors.New("geo: random point not found")
+
+// Bounds ограничивающий прямоугольник
+type Bounds struct {
+	Min Point `json:"min"`
+	Max Point `json:"max"`
+}
+
+func boundsOf(points []Point) Bounds {
+	if len(points) == 0 {
+		return Bounds{}
+	}
+
+	b := Bounds{Min: points[0], Max: points[0]}
+	for i := 1; i < len(points); i++ {
+		b = b.extend(points[i])
+	}
+
+	return b
+}
+
+func (b Bounds) extend(point Point) Bounds {
+	if point.Lat < b.Min.Lat {
+		b.Min.Lat = point.Lat
+	}
+	if point.Lng < b.Min.Lng {
+		b.Min.Lng = point.Lng
+	}
+	if point.Lat > b.Max.Lat {
+		b.Max.Lat = point.Lat
+	}
+	if point.Lng > b.Max.Lng {
+		b.Max.Lng = point.Lng
+	}
+
+	return b
+}
+
+func (b Bounds) union(other Bounds) Bounds {
+	return b.extend(other.Min).extend(other.Max)
+}
+
+// Contains проверяет, находится ли точка внутри прямоугольника
+func (b Bounds) Contains(point Point) bool {
+	return point.Lat >= b.Min.Lat && point.Lat <= b.Max.Lat &&
+		point.Lng >= b.Min.Lng && point.Lng <= b.Max.Lng
+}
+
+func (b Bounds) intersects(other Bounds) bool {
+	return b.Min.Lat <= other.Max.Lat && b.Max.Lat >= other.Min.Lat &&
+		b.Min.Lng <= other.Max.Lng && b.Max.Lng >= other.Min.Lng
+}
+
+// randomPointIn равномерно выбирает точку внутри ограничивающего прямоугольника
+// и отбрасывает ее, если она не попала в фигуру
+func randomPointIn(b Bounds, contains func(Point) bool, rnd random.Rand) (Point, error) {
+	for i := 0; i < maxRandomPointAttempts; i++ {
+		point := Point{
+			Lat: b.Min.Lat + rnd.Float64()*(b.Max.Lat-b.Min.Lat),
+			Lng: b.Min.Lng + rnd.Float64()*(b.Max.Lng-b.Min.Lng),
+		}
+
+		if contains(point) {
+			return point, nil
+		}
+	}
+
+	return Point{}, ErrRandomPointNotFound
+}
@@ -0,0 +1,70 @@
+package geo
+
+import (
+	"github.com/GoGerman/geo-task/random"
+	"math"
+	"testing"
+)
+
+func TestRandomPointInIsUniform(t *testing.T) {
+	// L-образная зона: нижняя полоса вдвое больше по площади, чем верхний квадрат
+	zone := NewMultiPolygon("l-shape", [][][]Point{{{
+		{Lat: 0, Lng: 0},
+		{Lat: 0, Lng: 2},
+		{Lat: 1, Lng: 2},
+		{Lat: 1, Lng: 1},
+		{Lat: 2, Lng: 1},
+		{Lat: 2, Lng: 0},
+	}}}, true)
+
+	const samples = 30000
+	rnd := random.New(42)
+	lower := 0
+	for i := 0; i < samples; i++ {
+		point, err := zone.RandomPoint(rnd)
+		if err != nil {
+			t.Fatalf("RandomPoint() error = %v", err)
+		}
+
+		if point.Lat < 1 {
+			lower++
+		}
+	}
+
+	// нижняя полоса занимает 2/3 площади, при равномерном распределении в нее попадает 2/3 точек
+	got := float64(lower) / samples
+	if math.Abs(got-2.0/3) > 0.02 {
+		t.Errorf("share of points in lower strip = %.3f, want %.3f", got, 2.0/3)
+	}
+}
+
+func TestRandomPointInIsReproducible(t *testing.T) {
+	b := Bounds{Min: Point{Lat: 0, Lng: 0}, Max: Point{Lat: 1, Lng: 1}}
+	all := func(Point) bool { return true }
+
+	first, second := random.New(7), random.New(7)
+	for i := 0; i < 100; i++ {
+		p1, err := randomPointIn(b, all, first)
+		if err != nil {
+			t.Fatalf("randomPointIn() error = %v", err)
+		}
+
+		p2, err := randomPointIn(b, all, second)
+		if err != nil {
+			t.Fatalf("randomPointIn() error = %v", err)
+		}
+
+		if p1 != p2 {
+			t.Fatalf("point %d differs for the same seed: %v != %v", i, p1, p2)
+		}
+	}
+}
+
+func TestRandomPointInGivesUp(t *testing.T) {
+	b := Bounds{Min: Point{Lat: 0, Lng: 0}, Max: Point{Lat: 1, Lng: 1}}
+
+	_, err := randomPointIn(b, func(Point) bool { return false }, random.New(1))
+	if err != ErrRandomPointNotFound {
+		t.Errorf("randomPointIn() error = %v, want %v", err, ErrRandomPointNotFound)
+	}
+}
@@ -0,0 +1,26 @@
+package geo
+
+import "math"
+
+// EarthRadius средний радиус Земли в метрах
+const EarthRadius = 6371000.0
+
+// Distance возвращает расстояние между точками по поверхности Земли в метрах (формула гаверсинусов)
+func Distance(a, b Point) float64 {
+	lat1 := a.Lat * math.Pi / 180
+	lat2 := b.Lat * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Interpolate возвращает точку на отрезке ab, t = 0 соответствует a, t = 1 - b
+func Interpolate(a, b Point, t float64) Point {
+	return Point{
+		Lat: a.Lat + t*(b.Lat-a.Lat),
+		Lng: a.Lng + t*(b.Lng-a.Lng),
+	}
+}
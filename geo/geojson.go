@@ -0,0 +1,54 @@
+package geo
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// MarshalFeatureCollection сериализует описания зон в GeoJSON FeatureCollection.
+// active содержит признак действия для зон с расписанием, может быть nil
+func MarshalFeatureCollection(specs []ZoneSpec, active map[string]bool) ([]byte, error) {
+	fc := featureCollection{
+		Type:     "FeatureCollection",
+		Features: make([]feature, 0, len(specs)),
+	}
+
+	for i := range specs {
+		properties := map[string]interface{}{
+			PropertyName:    specs[i].Name,
+			PropertyAllowed: specs[i].Allowed,
+		}
+
+		if specs[i].Overlay {
+			properties[PropertyOverlay] = true
+		}
+
+		if len(specs[i].Attributes) > 0 {
+			properties[PropertyAttributes] = specs[i].Attributes
+		}
+
+		if specs[i].Schedule != nil {
+			properties[PropertySchedule] = specs[i].Schedule
+		}
+
+		if v, ok := active[specs[i].ID]; ok {
+			properties[PropertyActive] = v
+		}
+
+		fc.Features = append(fc.Features, feature{
+			Type:       "Feature",
+			ID:         specs[i].ID,
+			Properties: properties,
+			Geometry:   specs[i].Geometry,
+		})
+	}
+
+	return json.Marshal(fc)
+}
+
+func etagOf(data []byte) string {
+	sum := sha1.Sum(data)
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,156 @@
+package geo
+
+import (
+	"github.com/GoGerman/geo-task/random"
+	"math"
+)
+
+// DefaultIndexCellSize размер ячейки сетки индекса в градусах (~1 км)
+const DefaultIndexCellSize = 0.01
+
+type cellKey struct {
+	lat int
+	lng int
+}
+
+// ZoneIndex сеточный индекс зон для быстрой проверки вхождения точки.
+// Каждая зона регистрируется во всех ячейках, которые пересекает ее ограничивающий прямоугольник,
+// поэтому на проверку точки приходится только несколько кандидатов из одной ячейки
+type ZoneIndex struct {
+	zones    []PolygonChecker
+	bounds   []Bounds
+	cells    map[cellKey][]int
+	cellSize float64
+	total    Bounds
+	allowed  bool
+}
+
+// NewZoneIndex строит индекс по списку зон, индекс сам реализует PolygonChecker как объединение зон
+func NewZoneIndex(zones []PolygonChecker, cellSize float64) *ZoneIndex {
+	if cellSize <= 0 {
+		cellSize = DefaultIndexCellSize
+	}
+
+	idx := &ZoneIndex{
+		zones:    zones,
+		bounds:   make([]Bounds, len(zones)),
+		cells:    make(map[cellKey][]int),
+		cellSize: cellSize,
+		allowed:  len(zones) > 0,
+	}
+
+	for i := range zones {
+		b := zones[i].Bounds()
+		idx.bounds[i] = b
+
+		if i == 0 {
+			idx.total = b
+		} else {
+			idx.total = idx.total.union(b)
+		}
+
+		// объединение разрешено, только если разрешены все зоны
+		idx.allowed = idx.allowed && zones[i].Allowed()
+
+		minCell := idx.cell(b.Min)
+		maxCell := idx.cell(b.Max)
+		for lat := minCell.lat; lat <= maxCell.lat; lat++ {
+			for lng := minCell.lng; lng <= maxCell.lng; lng++ {
+				key := cellKey{lat: lat, lng: lng}
+				idx.cells[key] = append(idx.cells[key], i)
+			}
+		}
+	}
+
+	return idx
+}
+
+func (idx *ZoneIndex) cell(point Point) cellKey {
+	return cellKey{
+		lat: int(math.Floor(point.Lat / idx.cellSize)),
+		lng: int(math.Floor(point.Lng / idx.cellSize)),
+	}
+}
+
+// Query возвращает зоны, содержащие точку
+func (idx *ZoneIndex) Query(point Point) []PolygonChecker {
+	var res []PolygonChecker
+
+	for _, i := range idx.cells[idx.cell(point)] {
+		if idx.bounds[i].Contains(point) && idx.zones[i].Contains(point) {
+			res = append(res, idx.zones[i])
+		}
+	}
+
+	return res
+}
+
+// QueryBounds возвращает зоны, ограничивающий прямоугольник которых пересекается с b
+func (idx *ZoneIndex) QueryBounds(b Bounds) []PolygonChecker {
+	var res []PolygonChecker
+	seen := make(map[int]struct{})
+
+	minCell := idx.cell(b.Min)
+	maxCell := idx.cell(b.Max)
+	for lat := minCell.lat; lat <= maxCell.lat; lat++ {
+		for lng := minCell.lng; lng <= maxCell.lng; lng++ {
+			for _, i := range idx.cells[cellKey{lat: lat, lng: lng}] {
+				if _, ok := seen[i]; ok {
+					continue
+				}
+				seen[i] = struct{}{}
+
+				if idx.bounds[i].intersects(b) {
+					res = append(res, idx.zones[i])
+				}
+			}
+		}
+	}
+
+	return res
+}
+
+// Zones возвращает все зоны индекса
+func (idx *ZoneIndex) Zones() []PolygonChecker {
+	return idx.zones
+}
+
+// ID индекс не является самостоятельной зоной, поэтому идентификатора у него нет
+func (idx *ZoneIndex) ID() string {
+	return ""
+}
+
+func (idx *ZoneIndex) Contains(point Point) bool {
+	for _, i := range idx.cells[idx.cell(point)] {
+		if idx.bounds[i].Contains(point) && idx.zones[i].Contains(point) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (idx *ZoneIndex) Allowed() bool {
+	return idx.allowed
+}
+
+func (idx *ZoneIndex) Bounds() Bounds {
+	return idx.total
+}
+
+func (idx *ZoneIndex) Rings() [][]Point {
+	var rings [][]Point
+	for i := range idx.zones {
+		rings = append(rings, idx.zones[i].Rings()...)
+	}
+
+	return rings
+}
+
+func (idx *ZoneIndex) RandomPoint(rnd random.Rand) (Point, error) {
+	if len(idx.zones) == 0 {
+		return Point{}, ErrRandomPointNotFound
+	}
+
+	return randomPointIn(idx.total, idx.Contains, rnd)
+}
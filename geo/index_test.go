@@ -0,0 +1,186 @@
+package geo
+
+import (
+	"fmt"
+	"github.com/GoGerman/geo-task/random"
+	"math"
+	"sort"
+	"testing"
+)
+
+// cityCenter и cityRadius задают разрешенную зону размером с город, в градусах
+var cityCenter = Point{Lat: 59.93, Lng: 30.33}
+
+const cityRadius = 0.15
+
+// cityZone возвращает разрешенную зону в виде правильного многоугольника из vertices вершин
+func cityZone(vertices int, rnd random.Rand) *MultiPolygon {
+	ring := make([]Point, vertices)
+	for i := range ring {
+		angle := 2 * math.Pi * float64(i) / float64(vertices)
+		ring[i] = Point{
+			Lat: cityCenter.Lat + cityRadius*math.Sin(angle),
+			Lng: cityCenter.Lng + cityRadius*math.Cos(angle),
+		}
+	}
+
+	return NewMultiPolygon("city", [][][]Point{{ring}}, true)
+}
+
+// restrictedZones возвращает n случайных запрещенных зон размером от 100 до 500 м внутри города
+func restrictedZones(n int, rnd random.Rand) []PolygonChecker {
+	zones := make([]PolygonChecker, n)
+	for i := range zones {
+		size := 0.001 + rnd.Float64()*0.004
+		lat := cityCenter.Lat - cityRadius/2 + rnd.Float64()*cityRadius
+		lng := cityCenter.Lng - cityRadius/2 + rnd.Float64()*cityRadius
+		zones[i] = NewMultiPolygon(fmt.Sprintf("zone-%d", i), [][][]Point{{square(lat, lng, size)}}, false)
+	}
+
+	return zones
+}
+
+// randomCityPoint возвращает случайную точку в ограничивающем прямоугольнике города
+func randomCityPoint(rnd random.Rand) Point {
+	return Point{
+		Lat: cityCenter.Lat - cityRadius + rnd.Float64()*2*cityRadius,
+		Lng: cityCenter.Lng - cityRadius + rnd.Float64()*2*cityRadius,
+	}
+}
+
+func zoneIDs(zones []PolygonChecker) []string {
+	ids := make([]string, 0, len(zones))
+	for i := range zones {
+		ids = append(ids, zones[i].ID())
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+func TestZoneIndexAgreesWithLinearScan(t *testing.T) {
+	rnd := random.New(1)
+	allowed := cityZone(58, rnd)
+	zones := restrictedZones(300, rnd)
+	indexed := []PolygonChecker{NewZoneIndex(zones, DefaultIndexCellSize)}
+
+	for i := 0; i < 20000; i++ {
+		point := randomCityPoint(rnd)
+
+		linear := CheckPointIsAllowed(point, allowed, zones)
+		if got := CheckPointIsAllowed(point, allowed, indexed); got != linear {
+			t.Fatalf("point %v: indexed check = %v, linear check = %v", point, got, linear)
+		}
+
+		want := fmt.Sprint(zoneIDs(ZonesAt(point, zones)))
+		if got := fmt.Sprint(zoneIDs(ZonesAt(point, indexed))); got != want {
+			t.Fatalf("point %v: indexed zones = %s, linear zones = %s", point, got, want)
+		}
+	}
+}
+
+func TestZoneIndexQueryBounds(t *testing.T) {
+	rnd := random.New(2)
+	zones := restrictedZones(300, rnd)
+	idx := NewZoneIndex(zones, DefaultIndexCellSize)
+
+	for i := 0; i < 1000; i++ {
+		a, b := randomCityPoint(rnd), randomCityPoint(rnd)
+		query := segmentBounds(a, b)
+
+		var want []PolygonChecker
+		for j := range zones {
+			if zones[j].Bounds().intersects(query) {
+				want = append(want, zones[j])
+			}
+		}
+
+		if got, want := fmt.Sprint(zoneIDs(idx.QueryBounds(query))), fmt.Sprint(zoneIDs(want)); got != want {
+			t.Fatalf("QueryBounds(%v) = %s, want %s", query, got, want)
+		}
+	}
+}
+
+func TestZoneIndexUnion(t *testing.T) {
+	rnd := random.New(3)
+	zones := []PolygonChecker{
+		NewMultiPolygon("a", [][][]Point{{square(0, 0, 1)}}, false),
+		NewMultiPolygon("b", [][][]Point{{square(5, 5, 1)}}, false),
+	}
+	idx := NewZoneIndex(zones, DefaultIndexCellSize)
+
+	if idx.Allowed() {
+		t.Error("index of disabled zones is allowed")
+	}
+
+	want := Bounds{Min: Point{Lat: 0, Lng: 0}, Max: Point{Lat: 6, Lng: 6}}
+	if got := idx.Bounds(); got != want {
+		t.Errorf("Bounds() = %v, want %v", got, want)
+	}
+
+	for i := 0; i < 100; i++ {
+		point, err := idx.RandomPoint(rnd)
+		if err != nil {
+			t.Fatalf("RandomPoint() error = %v", err)
+		}
+		if !idx.Contains(point) {
+			t.Fatalf("RandomPoint() = %v, outside of zones", point)
+		}
+	}
+}
+
+func BenchmarkCheckPointIsAllowed(b *testing.B) {
+	for _, n := range []int{10, 100, 500, 1000} {
+		rnd := random.New(1)
+		allowed := cityZone(58, rnd)
+		zones := restrictedZones(n, rnd)
+		indexed := []PolygonChecker{NewZoneIndex(zones, DefaultIndexCellSize)}
+
+		points := make([]Point, 1024)
+		for i := range points {
+			points[i] = randomCityPoint(rnd)
+		}
+
+		b.Run(fmt.Sprintf("linear/zones=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				CheckPointIsAllowed(points[i%len(points)], allowed, zones)
+			}
+		})
+
+		b.Run(fmt.Sprintf("index/zones=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				CheckPointIsAllowed(points[i%len(points)], allowed, indexed)
+			}
+		})
+	}
+}
+
+func BenchmarkZoneContains(b *testing.B) {
+	for _, n := range []int{100, 500, 1000} {
+		rnd := random.New(1)
+		zones := restrictedZones(n, rnd)
+		idx := NewZoneIndex(zones, DefaultIndexCellSize)
+
+		points := make([]Point, 1024)
+		for i := range points {
+			points[i] = randomCityPoint(rnd)
+		}
+
+		b.Run(fmt.Sprintf("linear/zones=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				point := points[i%len(points)]
+				for j := range zones {
+					if zones[j].Contains(point) {
+						break
+					}
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("index/zones=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				idx.Contains(points[i%len(points)])
+			}
+		})
+	}
+}
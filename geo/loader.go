@@ -0,0 +1,328 @@
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/GoGerman/geo-task/clock"
+	"io"
+	"os"
+	"strconv"
+)
+
+const (
+	GeometryPolygon      = "Polygon"
+	GeometryMultiPolygon = "MultiPolygon"
+)
+
+// AllowedZoneID идентификатор объединенной разрешенной зоны, если она собрана из нескольких Feature
+const AllowedZoneID = "allowed"
+
+const (
+	PropertyAllowed    = "allowed"    // свойство GeoJSON Feature, определяющее разрешена ли зона
+	PropertyName       = "name"       // свойство GeoJSON Feature с названием зоны
+	PropertySchedule   = "schedule"   // свойство GeoJSON Feature с расписанием действия зоны
+	PropertyActive     = "active"     // действует ли зона с расписанием в текущий момент, только при сериализации
+	PropertyOverlay    = "overlay"    // свойство GeoJSON Feature, зона только задает атрибуты и не ограничивает перемещение
+	PropertyAttributes = "attributes" // свойство GeoJSON Feature с числовыми атрибутами зоны
+)
+
+var (
+	ErrNoAllowedZone        = errors.New("geojson: no allowed zone found")
+	ErrAllowedZoneScheduled = errors.New("schedule is supported only for disabled and overlay zones")
+)
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string                 `json:"type"`
+	ID         interface{}            `json:"id"`
+	Properties map[string]interface{} `json:"properties"`
+	Geometry   Geometry               `json:"geometry"`
+}
+
+// Geometry GeoJSON геометрия Polygon или MultiPolygon
+type Geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// ZoneSpec описание зоны, из которого строится PolygonChecker
+type ZoneSpec struct {
+	ID         string
+	Name       string
+	Allowed    bool
+	Geometry   Geometry
+	Schedule   *Schedule  // расписание действия зоны, nil - зона действует всегда
+	Overlay    bool       // зона только задает атрибуты, например район с повышенной стоимостью доставки
+	Attributes Attributes // атрибуты зоны, применяются к точкам внутри нее
+}
+
+// LoadZoneSpecs читает описания зон из GeoJSON файла
+func LoadZoneSpecs(path string) ([]ZoneSpec, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadZoneSpecs(f)
+}
+
+// ReadZoneSpecs разбирает GeoJSON FeatureCollection с геометриями Polygon и MultiPolygon в описания зон,
+// геометрия на этом шаге не проверяется
+func ReadZoneSpecs(r io.Reader) ([]ZoneSpec, error) {
+	var fc featureCollection
+
+	err := json.NewDecoder(r).Decode(&fc)
+	if err != nil {
+		return nil, err
+	}
+
+	if fc.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("geojson: unexpected type %q, want FeatureCollection", fc.Type)
+	}
+
+	specs := make([]ZoneSpec, 0, len(fc.Features))
+	for i := range fc.Features {
+		overlay, _ := fc.Features[i].Properties[PropertyOverlay].(bool)
+
+		// у зоны атрибутов признак allowed не обязателен, она не влияет на разрешенную область
+		allowed, ok := fc.Features[i].Properties[PropertyAllowed].(bool)
+		if !ok && !overlay {
+			return nil, fmt.Errorf("geojson: feature %d: property %q must be boolean", i, PropertyAllowed)
+		}
+
+		name, _ := fc.Features[i].Properties[PropertyName].(string)
+
+		schedule, err := fc.Features[i].schedule()
+		if err != nil {
+			return nil, fmt.Errorf("geojson: feature %d: %w", i, err)
+		}
+
+		attributes, err := fc.Features[i].attributes()
+		if err != nil {
+			return nil, fmt.Errorf("geojson: feature %d: %w", i, err)
+		}
+
+		specs = append(specs, ZoneSpec{
+			ID:         fc.Features[i].id(i),
+			Name:       name,
+			Allowed:    allowed,
+			Geometry:   fc.Features[i].Geometry,
+			Schedule:   schedule,
+			Overlay:    overlay,
+			Attributes: attributes,
+		})
+	}
+
+	return specs, nil
+}
+
+// BuildZones строит зоны по описаниям.
+// Все разрешенные зоны объединяются в одну, например город с островами.
+// Запрещенные зоны с расписанием проверяются по времени из clk, зоны атрибутов пропускаются
+func BuildZones(specs []ZoneSpec, clk clock.Clock) (PolygonChecker, []PolygonChecker, error) {
+	var allowedParts [][][]Point
+	var allowedIDs []string
+	var disabledZones []PolygonChecker
+	var invalid ValidationErrors
+
+	for i := range specs {
+		parts, err := specs[i].Geometry.parts()
+		if err != nil {
+			return nil, nil, fmt.Errorf("geojson: zone %q: %w", specs[i].ID, err)
+		}
+
+		// ошибки валидации собираются по всем зонам, чтобы сообщить обо всех проблемах сразу
+		parts, err = ValidateParts(specs[i].ID, parts)
+		if err != nil {
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) {
+				return nil, nil, err
+			}
+			invalid = append(invalid, verrs...)
+			continue
+		}
+
+		if specs[i].Overlay {
+			continue
+		}
+
+		if specs[i].Allowed && specs[i].Schedule != nil {
+			return nil, nil, fmt.Errorf("zone %q: %w", specs[i].ID, ErrAllowedZoneScheduled)
+		}
+
+		if specs[i].Allowed {
+			allowedParts = append(allowedParts, parts...)
+			allowedIDs = append(allowedIDs, specs[i].ID)
+		} else {
+			disabledZones = append(disabledZones, NewMultiPolygon(specs[i].ID, parts, false))
+		}
+	}
+
+	if len(invalid) > 0 {
+		return nil, nil, invalid
+	}
+
+	if len(allowedParts) == 0 {
+		return nil, nil, ErrNoAllowedZone
+	}
+
+	allowedID := AllowedZoneID
+	if len(allowedIDs) == 1 {
+		allowedID = allowedIDs[0]
+	}
+
+	allowedZone := NewMultiPolygon(allowedID, allowedParts, true)
+
+	// зоны проверяются до применения расписания, чтобы результат не зависел от времени загрузки
+	err := ValidateZones(allowedZone, disabledZones)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range disabledZones {
+		spec := specByID(specs, disabledZones[i].ID())
+		if spec == nil || spec.Schedule == nil {
+			continue
+		}
+
+		disabledZones[i], err = NewScheduledZone(disabledZones[i], spec.Schedule, clk)
+		if err != nil {
+			return nil, nil, fmt.Errorf("zone %q: invalid schedule: %w", spec.ID, err)
+		}
+	}
+
+	return allowedZone, disabledZones, nil
+}
+
+func specByID(specs []ZoneSpec, id string) *ZoneSpec {
+	for i := range specs {
+		if specs[i].ID == id {
+			return &specs[i]
+		}
+	}
+
+	return nil
+}
+
+// schedule читает расписание из свойств Feature
+func (f feature) schedule() (*Schedule, error) {
+	v, ok := f.Properties[PropertySchedule]
+	if !ok || v == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var schedule Schedule
+	err = json.Unmarshal(data, &schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+// attributes читает числовые атрибуты из свойств Feature
+func (f feature) attributes() (Attributes, error) {
+	v, ok := f.Properties[PropertyAttributes]
+	if !ok || v == nil {
+		return nil, nil
+	}
+
+	values, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("property %q must be object", PropertyAttributes)
+	}
+
+	res := make(Attributes, len(values))
+	for k, value := range values {
+		number, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("attribute %q must be number", k)
+		}
+		res[k] = number
+	}
+
+	return res, nil
+}
+
+// id возвращает идентификатор Feature, если он не задан - порядковый номер в коллекции
+func (f feature) id(i int) string {
+	switch id := f.ID.(type) {
+	case string:
+		return id
+	case float64:
+		return strconv.FormatFloat(id, 'f', -1, 64)
+	}
+
+	return fmt.Sprintf("zone-%d", i)
+}
+
+// parts возвращает части геометрии, каждая часть - внешний контур и дыры
+func (g Geometry) parts() ([][][]Point, error) {
+	// координаты в GeoJSON задаются в порядке [lng, lat]
+	var polygons [][][][]float64
+
+	switch g.Type {
+	case GeometryPolygon:
+		var polygon [][][]float64
+		err := json.Unmarshal(g.Coordinates, &polygon)
+		if err != nil {
+			return nil, err
+		}
+		polygons = append(polygons, polygon)
+	case GeometryMultiPolygon:
+		err := json.Unmarshal(g.Coordinates, &polygons)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %q", g.Type)
+	}
+
+	res := make([][][]Point, 0, len(polygons))
+	for i := range polygons {
+		if len(polygons[i]) == 0 {
+			return nil, errors.New("polygon without rings")
+		}
+
+		rings := make([][]Point, 0, len(polygons[i]))
+		for j := range polygons[i] {
+			points, err := ringToPoints(polygons[i][j])
+			if err != nil {
+				return nil, err
+			}
+			rings = append(rings, points)
+		}
+
+		res = append(res, rings)
+	}
+
+	return res, nil
+}
+
+func ringToPoints(ring [][]float64) ([]Point, error) {
+	points := make([]Point, 0, len(ring))
+	for i := range ring {
+		if len(ring[i]) < 2 {
+			return nil, fmt.Errorf("position %d: expected [lng, lat]", i)
+		}
+		points = append(points, Point{Lat: ring[i][1], Lng: ring[i][0]})
+	}
+
+	// в GeoJSON кольцо замкнуто, последняя точка совпадает с первой
+	if len(points) > 1 && points[0] == points[len(points)-1] {
+		points = points[:len(points)-1]
+	}
+
+	return points, nil
+}
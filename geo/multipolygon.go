@@ -0,0 +1,127 @@
+package geo
+
+import (
+	"github.com/GoGerman/geo-task/random"
+	geo "github.com/kellydunn/golang-geo"
+)
+
+// MultiPolygon зона из нескольких частей, каждая часть состоит из внешнего контура и внутренних вырезов (дыр)
+type MultiPolygon struct {
+	id      string
+	parts   []polygonPart
+	bounds  Bounds
+	allowed bool
+}
+
+type polygonPart struct {
+	outer  *geo.Polygon
+	holes  []*geo.Polygon
+	rings  [][]Point
+	bounds Bounds
+}
+
+// NewMultiPolygon создает зону из частей, где каждая часть - список колец:
+// первое кольцо внешний контур, остальные - дыры внутри него
+func NewMultiPolygon(id string, parts [][][]Point, allowed bool) *MultiPolygon {
+	m := &MultiPolygon{
+		id:      id,
+		parts:   make([]polygonPart, 0, len(parts)),
+		allowed: allowed,
+	}
+
+	for i := range parts {
+		if len(parts[i]) == 0 {
+			continue
+		}
+
+		part := polygonPart{
+			outer:  newGeoPolygon(parts[i][0]),
+			holes:  make([]*geo.Polygon, 0, len(parts[i])-1),
+			rings:  parts[i],
+			bounds: boundsOf(parts[i][0]),
+		}
+		for j := 1; j < len(parts[i]); j++ {
+			part.holes = append(part.holes, newGeoPolygon(parts[i][j]))
+		}
+
+		if len(m.parts) == 0 {
+			m.bounds = part.bounds
+		} else {
+			m.bounds = m.bounds.union(part.bounds)
+		}
+
+		m.parts = append(m.parts, part)
+	}
+
+	return m
+}
+
+func newGeoPolygon(points []Point) *geo.Polygon {
+	geoPoints := make([]*geo.Point, len(points))
+	for i := 0; i < len(points); i++ {
+		geoPoints[i] = geo.NewPoint(points[i].Lat, points[i].Lng)
+	}
+
+	return geo.NewPolygon(geoPoints)
+}
+
+func (m *MultiPolygon) ID() string {
+	return m.id
+}
+
+func (m *MultiPolygon) Contains(point Point) bool {
+	if !m.bounds.Contains(point) {
+		return false
+	}
+
+	gp := geo.NewPoint(point.Lat, point.Lng)
+
+	for i := range m.parts {
+		if m.parts[i].bounds.Contains(point) && m.parts[i].contains(gp) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p polygonPart) contains(point *geo.Point) bool {
+	if !p.outer.Contains(point) {
+		return false
+	}
+
+	for i := range p.holes {
+		if p.holes[i].Contains(point) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m *MultiPolygon) Allowed() bool {
+	return m.allowed
+}
+
+// Bounds возвращает ограничивающий прямоугольник всех частей
+func (m *MultiPolygon) Bounds() Bounds {
+	return m.bounds
+}
+
+func (m *MultiPolygon) Rings() [][]Point {
+	var rings [][]Point
+	for i := range m.parts {
+		rings = append(rings, m.parts[i].rings...)
+	}
+
+	return rings
+}
+
+// RandomPoint генерирует равномерно распределенную случайную точку внутри зоны, не попадающую в дыры
+func (m *MultiPolygon) RandomPoint(rnd random.Rand) (Point, error) {
+	if len(m.parts) == 0 {
+		return Point{}, ErrRandomPointNotFound
+	}
+
+	return randomPointIn(m.bounds, m.Contains, rnd)
+}
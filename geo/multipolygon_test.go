@@ -0,0 +1,90 @@
+package geo
+
+import (
+	"github.com/GoGerman/geo-task/random"
+	"testing"
+)
+
+// square возвращает квадратное кольцо с левым нижним углом в (lat, lng) и стороной size градусов
+func square(lat, lng, size float64) []Point {
+	return []Point{
+		{Lat: lat, Lng: lng},
+		{Lat: lat, Lng: lng + size},
+		{Lat: lat + size, Lng: lng + size},
+		{Lat: lat + size, Lng: lng},
+	}
+}
+
+func TestMultiPolygonContains(t *testing.T) {
+	// две части: квадрат с дырой в центре и отдельный остров
+	zone := NewMultiPolygon("city", [][][]Point{
+		{square(0, 0, 10), square(4, 4, 2)},
+		{square(20, 20, 2)},
+	}, true)
+
+	tests := []struct {
+		name  string
+		point Point
+		want  bool
+	}{
+		{name: "inside outer ring", point: Point{Lat: 1, Lng: 1}, want: true},
+		{name: "inside hole", point: Point{Lat: 5, Lng: 5}, want: false},
+		{name: "inside second part", point: Point{Lat: 21, Lng: 21}, want: true},
+		{name: "between parts", point: Point{Lat: 15, Lng: 15}, want: false},
+		{name: "outside bounds", point: Point{Lat: -1, Lng: 5}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := zone.Contains(tt.point); got != tt.want {
+				t.Errorf("Contains(%v) = %v, want %v", tt.point, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultiPolygonBoundsAndRings(t *testing.T) {
+	zone := NewMultiPolygon("city", [][][]Point{
+		{square(0, 0, 10), square(4, 4, 2)},
+		{square(20, 20, 2)},
+	}, true)
+
+	want := Bounds{Min: Point{Lat: 0, Lng: 0}, Max: Point{Lat: 22, Lng: 22}}
+	if got := zone.Bounds(); got != want {
+		t.Errorf("Bounds() = %v, want %v", got, want)
+	}
+
+	if got := len(zone.Rings()); got != 3 {
+		t.Errorf("len(Rings()) = %d, want 3", got)
+	}
+}
+
+func TestMultiPolygonRandomPointAvoidsHoles(t *testing.T) {
+	zone := NewMultiPolygon("city", [][][]Point{
+		{square(0, 0, 10), square(2, 2, 6)},
+	}, true)
+	rnd := random.New(1)
+
+	for i := 0; i < 1000; i++ {
+		point, err := zone.RandomPoint(rnd)
+		if err != nil {
+			t.Fatalf("RandomPoint() error = %v", err)
+		}
+
+		if !zone.Contains(point) {
+			t.Fatalf("RandomPoint() = %v, outside of zone", point)
+		}
+	}
+}
+
+func TestMultiPolygonWithoutParts(t *testing.T) {
+	zone := NewMultiPolygon("empty", nil, false)
+
+	if zone.Contains(Point{}) {
+		t.Error("empty zone contains point")
+	}
+
+	if _, err := zone.RandomPoint(random.New(1)); err != ErrRandomPointNotFound {
+		t.Errorf("RandomPoint() error = %v, want %v", err, ErrRandomPointNotFound)
+	}
+}
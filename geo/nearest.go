@@ -0,0 +1,163 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+const (
+	// snapOffset насколько точка сдвигается за границу внутрь разрешенной области, в градусах (~10 см)
+	snapOffset = 1e-6
+	// maxSnapAttempts ограничение количества расширений набора границ, если после сдвига точка попадает в соседние запрещенные зоны
+	maxSnapAttempts = 10
+)
+
+var ErrAllowedPointNotFound = errors.New("geo: nearest allowed point not found")
+
+// NearestAllowedPoint возвращает ближайшую к point разрешенную точку:
+// если точка вне разрешенной зоны - ближайшую точку на ее границе,
+// если точка внутри запрещенной зоны - ближайшую точку снаружи этой зоны.
+// Соседние запрещенные зоны могут иметь общую границу, поэтому точки на границах перебираются от ближайшей,
+// а зоны, в которые попадают сдвинутые точки, добавляются к проверяемым границам
+func NearestAllowedPoint(point Point, allowedZone PolygonChecker, disabledZones []PolygonChecker) (Point, error) {
+	if CheckPointIsAllowed(point, allowedZone, disabledZones) {
+		return point, nil
+	}
+
+	var rings [][]Point
+	if !allowedZone.Contains(point) {
+		rings = append(rings, allowedZone.Rings()...)
+	}
+
+	seen := make(map[PolygonChecker]struct{})
+	addZonesAt := func(p Point) bool {
+		added := false
+		for _, zone := range ZonesAt(p, disabledZones) {
+			if _, ok := seen[zone]; ok {
+				continue
+			}
+			seen[zone] = struct{}{}
+			rings = append(rings, zone.Rings()...)
+			added = true
+		}
+
+		return added
+	}
+	addZonesAt(point)
+
+	for i := 0; i < maxSnapAttempts; i++ {
+		candidates := nearestOnRings(point, rings)
+		if len(candidates) == 0 {
+			return Point{}, ErrAllowedPointNotFound
+		}
+
+		var blocked []Point
+		for _, candidate := range candidates {
+			stop := stepPast(point, candidate)
+			if CheckPointIsAllowed(stop, allowedZone, disabledZones) {
+				return stop, nil
+			}
+			blocked = append(blocked, stop)
+		}
+
+		added := false
+		for _, stop := range blocked {
+			if addZonesAt(stop) {
+				added = true
+			}
+		}
+
+		if !added {
+			break
+		}
+	}
+
+	return Point{}, ErrAllowedPointNotFound
+}
+
+// ZonesAt возвращает зоны, содержащие точку; индексы зон раскрываются до отдельных зон
+func ZonesAt(point Point, zones []PolygonChecker) []PolygonChecker {
+	var res []PolygonChecker
+
+	for i := range zones {
+		if idx, ok := zones[i].(*ZoneIndex); ok {
+			res = append(res, idx.Query(point)...)
+			continue
+		}
+
+		if zones[i].Contains(point) {
+			res = append(res, zones[i])
+		}
+	}
+
+	return res
+}
+
+// stepPast сдвигает точку на границе чуть дальше по направлению движения from -> onBoundary
+func stepPast(from, onBoundary Point) Point {
+	scale := lngScale(from.Lat)
+	dx := (onBoundary.Lng - from.Lng) * scale
+	dy := onBoundary.Lat - from.Lat
+	length := math.Hypot(dx, dy)
+
+	if length == 0 {
+		return Point{Lat: onBoundary.Lat + snapOffset, Lng: onBoundary.Lng}
+	}
+
+	return Point{
+		Lat: onBoundary.Lat + dy/length*snapOffset,
+		Lng: onBoundary.Lng + dx/length*snapOffset/scale,
+	}
+}
+
+// nearestOnRings возвращает ближайшие к точке точки на каждой стороне колец, от ближайшей к дальней
+func nearestOnRings(point Point, rings [][]Point) []Point {
+	var candidates []Point
+
+	for _, ring := range rings {
+		for i := range ring {
+			a := ring[i]
+			b := ring[(i+1)%len(ring)]
+
+			candidates = append(candidates, nearestOnSegment(point, a, b))
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return planarDistance(point, candidates[i]) < planarDistance(point, candidates[j])
+	})
+
+	return candidates
+}
+
+// nearestOnSegment проекция точки на отрезок ab в локальной плоской системе координат,
+// долгота масштабируется на cos(широты), чтобы расстояния по осям были сопоставимы
+func nearestOnSegment(point, a, b Point) Point {
+	scale := lngScale(point.Lat)
+	abx := (b.Lng - a.Lng) * scale
+	aby := b.Lat - a.Lat
+	apx := (point.Lng - a.Lng) * scale
+	apy := point.Lat - a.Lat
+
+	lengthSq := abx*abx + aby*aby
+	if lengthSq == 0 {
+		return a
+	}
+
+	t := (apx*abx + apy*aby) / lengthSq
+	t = math.Max(0, math.Min(1, t))
+
+	return Point{
+		Lat: a.Lat + t*(b.Lat-a.Lat),
+		Lng: a.Lng + t*(b.Lng-a.Lng),
+	}
+}
+
+func planarDistance(a, b Point) float64 {
+	return math.Hypot((b.Lng-a.Lng)*lngScale(a.Lat), b.Lat-a.Lat)
+}
+
+func lngScale(lat float64) float64 {
+	return math.Cos(lat * math.Pi / 180)
+}
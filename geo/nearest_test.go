@@ -0,0 +1,75 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNearestAllowedPoint(t *testing.T) {
+	allowed := NewMultiPolygon("city", [][][]Point{{square(0, 0, 10)}}, true)
+	disabled := []PolygonChecker{NewZoneIndex([]PolygonChecker{
+		NewMultiPolygon("park", [][][]Point{{square(4, 4, 2)}}, false),
+		// две соседние зоны: выход из одной приводит в другую
+		NewMultiPolygon("west", [][][]Point{{square(1, 1, 1)}}, false),
+		NewMultiPolygon("east", [][][]Point{{square(1, 2, 1)}}, false),
+	}, DefaultIndexCellSize)}
+
+	tests := []struct {
+		name  string
+		point Point
+		want  Point // ожидаемая точка с точностью до сдвига за границу
+	}{
+		{name: "already allowed", point: Point{Lat: 8, Lng: 8}, want: Point{Lat: 8, Lng: 8}},
+		{name: "outside allowed zone", point: Point{Lat: 5, Lng: 12}, want: Point{Lat: 5, Lng: 10}},
+		{name: "outside allowed corner", point: Point{Lat: -1, Lng: -1}, want: Point{Lat: 0, Lng: 0}},
+		{name: "inside disabled zone", point: Point{Lat: 4.9, Lng: 4.2}, want: Point{Lat: 4.9, Lng: 4}},
+		{name: "inside adjacent disabled zones", point: Point{Lat: 1.6, Lng: 1.9}, want: Point{Lat: 2, Lng: 1.9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NearestAllowedPoint(tt.point, allowed, disabled)
+			if err != nil {
+				t.Fatalf("NearestAllowedPoint() error = %v", err)
+			}
+
+			if !CheckPointIsAllowed(got, allowed, disabled) {
+				t.Fatalf("NearestAllowedPoint() = %v, point is not allowed", got)
+			}
+
+			if d := planarDistance(got, tt.want); d > 0.01 {
+				t.Errorf("NearestAllowedPoint() = %v, want about %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNearestAllowedPointNotFound(t *testing.T) {
+	allowed := NewMultiPolygon("city", [][][]Point{{square(0, 0, 10)}}, true)
+	disabled := []PolygonChecker{NewMultiPolygon("everything", [][][]Point{{square(-1, -1, 12)}}, false)}
+
+	_, err := NearestAllowedPoint(Point{Lat: 5, Lng: 5}, allowed, disabled)
+	if err != ErrAllowedPointNotFound {
+		t.Errorf("NearestAllowedPoint() error = %v, want %v", err, ErrAllowedPointNotFound)
+	}
+}
+
+func TestNearestOnSegment(t *testing.T) {
+	a, b := Point{Lat: 0, Lng: 0}, Point{Lat: 0, Lng: 10}
+
+	tests := []struct {
+		point Point
+		want  Point
+	}{
+		{point: Point{Lat: 1, Lng: 5}, want: Point{Lat: 0, Lng: 5}},
+		{point: Point{Lat: 1, Lng: -5}, want: a},
+		{point: Point{Lat: -1, Lng: 15}, want: b},
+	}
+
+	for _, tt := range tests {
+		got := nearestOnSegment(tt.point, a, b)
+		if math.Abs(got.Lat-tt.want.Lat) > 1e-9 || math.Abs(got.Lng-tt.want.Lng) > 1e-9 {
+			t.Errorf("nearestOnSegment(%v) = %v, want %v", tt.point, got, tt.want)
+		}
+	}
+}
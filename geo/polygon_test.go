@@ -0,0 +1,39 @@
+package geo
+
+import (
+	"github.com/GoGerman/geo-task/random"
+	"testing"
+)
+
+func TestGetRandomAllowedLocationAvoidsDisabledZones(t *testing.T) {
+	rnd := random.New(1)
+	allowed := NewMultiPolygon("city", [][][]Point{{square(0, 0, 10)}}, true)
+	disabled := []PolygonChecker{
+		NewMultiPolygon("park", [][][]Point{{square(0, 0, 5)}}, false),
+		NewMultiPolygon("stadium", [][][]Point{{square(5, 5, 5)}}, false),
+	}
+
+	for i := 0; i < 1000; i++ {
+		point, err := GetRandomAllowedLocation(allowed, disabled, rnd)
+		if err != nil {
+			t.Fatalf("GetRandomAllowedLocation() error = %v", err)
+		}
+
+		if !CheckPointIsAllowed(point, allowed, disabled) {
+			t.Fatalf("GetRandomAllowedLocation() = %v, point is not allowed", point)
+		}
+	}
+}
+
+func TestGetRandomAllowedLocationFullyDisabled(t *testing.T) {
+	rnd := random.New(1)
+	allowed := NewMultiPolygon("city", [][][]Point{{square(0, 0, 10)}}, true)
+	disabled := []PolygonChecker{
+		NewMultiPolygon("everything", [][][]Point{{square(-1, -1, 12)}}, false),
+	}
+
+	_, err := GetRandomAllowedLocation(allowed, disabled, rnd)
+	if err != ErrRandomPointNotFound {
+		t.Errorf("GetRandomAllowedLocation() error = %v, want %v", err, ErrRandomPointNotFound)
+	}
+}
@@ -0,0 +1,184 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/GoGerman/geo-task/clock"
+	"strings"
+	"time"
+)
+
+const minutesPerDay = 24 * 60
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Schedule расписание действия зоны: набор окон в часовом поясе Timezone.
+// Зона действует, если текущее время попадает хотя бы в одно окно
+type Schedule struct {
+	Timezone string           `json:"timezone"` // например Europe/Moscow, по умолчанию UTC
+	Windows  []ScheduleWindow `json:"windows"`
+}
+
+// ScheduleWindow окно действия зоны с Start до End (формат HH:MM) в указанные дни недели или даты.
+// Если дни и даты не заданы - окно действует ежедневно. Если End не позже Start - окно переходит через полночь
+type ScheduleWindow struct {
+	Days  []string `json:"days,omitempty"`  // mon, tue, wed, thu, fri, sat, sun
+	Dates []string `json:"dates,omitempty"` // YYYY-MM-DD, например дни матчей
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+type compiledSchedule struct {
+	location *time.Location
+	windows  []compiledWindow
+}
+
+type compiledWindow struct {
+	days  map[time.Weekday]struct{}
+	dates map[string]struct{}
+	start int // минуты от начала суток
+	end   int
+}
+
+func (s *Schedule) compile() (*compiledSchedule, error) {
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.Windows) == 0 {
+		return nil, errors.New("schedule has no windows")
+	}
+
+	res := &compiledSchedule{location: location}
+	for i, w := range s.Windows {
+		cw := compiledWindow{
+			days:  make(map[time.Weekday]struct{}, len(w.Days)),
+			dates: make(map[string]struct{}, len(w.Dates)),
+		}
+
+		for _, day := range w.Days {
+			weekday, ok := weekdays[strings.ToLower(day)]
+			if !ok {
+				return nil, fmt.Errorf("window %d: unknown day %q", i, day)
+			}
+			cw.days[weekday] = struct{}{}
+		}
+
+		for _, date := range w.Dates {
+			_, err = time.Parse("2006-01-02", date)
+			if err != nil {
+				return nil, fmt.Errorf("window %d: %w", i, err)
+			}
+			cw.dates[date] = struct{}{}
+		}
+
+		cw.start, err = parseClock(w.Start)
+		if err != nil {
+			return nil, fmt.Errorf("window %d: %w", i, err)
+		}
+
+		cw.end, err = parseClock(w.End)
+		if err != nil {
+			return nil, fmt.Errorf("window %d: %w", i, err)
+		}
+
+		res.windows = append(res.windows, cw)
+	}
+
+	return res, nil
+}
+
+// parseClock переводит время HH:MM в минуты от начала суток, 24:00 допускается как конец суток
+func parseClock(v string) (int, error) {
+	var h, m int
+
+	_, err := fmt.Sscanf(v, "%d:%d", &h, &m)
+	if err != nil || h < 0 || m < 0 || m > 59 || h*60+m > minutesPerDay {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", v)
+	}
+
+	return h*60 + m, nil
+}
+
+func (s *compiledSchedule) active(t time.Time) bool {
+	local := t.In(s.location)
+	minute := local.Hour()*60 + local.Minute()
+
+	for i := range s.windows {
+		if s.windows[i].active(local, minute) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w compiledWindow) active(local time.Time, minute int) bool {
+	if w.start < w.end {
+		return w.matchesDay(local) && minute >= w.start && minute < w.end
+	}
+
+	// окно через полночь: вечер дня окна или утро следующего дня
+	return (w.matchesDay(local) && minute >= w.start) ||
+		(w.matchesDay(local.AddDate(0, 0, -1)) && minute < w.end)
+}
+
+func (w compiledWindow) matchesDay(local time.Time) bool {
+	if len(w.days) == 0 && len(w.dates) == 0 {
+		return true
+	}
+
+	if _, ok := w.days[local.Weekday()]; ok {
+		return true
+	}
+
+	_, ok := w.dates[local.Format("2006-01-02")]
+
+	return ok
+}
+
+// ScheduledZone зона, которая действует только по расписанию.
+// Вне расписания зона не содержит точек и не имеет контуров, поэтому не влияет на проверки.
+// Ограничивающий прямоугольник возвращается всегда: сеточный индекс строится один раз для набора зон
+// и должен находить зону, когда она начнет действовать
+type ScheduledZone struct {
+	PolygonChecker
+	schedule *compiledSchedule
+	clock    clock.Clock
+}
+
+// NewScheduledZone оборачивает зону расписанием, текущее время берется из clock
+func NewScheduledZone(zone PolygonChecker, schedule *Schedule, clock clock.Clock) (*ScheduledZone, error) {
+	compiled, err := schedule.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScheduledZone{PolygonChecker: zone, schedule: compiled, clock: clock}, nil
+}
+
+// ActiveAt проверяет, действует ли зона в момент t
+func (z *ScheduledZone) ActiveAt(t time.Time) bool {
+	return z.schedule.active(t)
+}
+
+func (z *ScheduledZone) Contains(point Point) bool {
+	return z.ActiveAt(z.clock.Now()) && z.PolygonChecker.Contains(point)
+}
+
+func (z *ScheduledZone) Rings() [][]Point {
+	if !z.ActiveAt(z.clock.Now()) {
+		return nil
+	}
+
+	return z.PolygonChecker.Rings()
+}
@@ -0,0 +1,139 @@
+package geo
+
+import (
+	"github.com/GoGerman/geo-task/clock"
+	"testing"
+	"time"
+)
+
+func TestScheduleActive(t *testing.T) {
+	// 2024-06-15 суббота
+	at := func(value string) time.Time {
+		t.Helper()
+		v, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		time     string
+		want     bool
+	}{
+		{
+			name:     "daily window inside",
+			schedule: Schedule{Windows: []ScheduleWindow{{Start: "10:00", End: "18:00"}}},
+			time:     "2024-06-15T12:00:00Z",
+			want:     true,
+		},
+		{
+			name:     "daily window end is exclusive",
+			schedule: Schedule{Windows: []ScheduleWindow{{Start: "10:00", End: "18:00"}}},
+			time:     "2024-06-15T18:00:00Z",
+		},
+		{
+			name:     "weekend only on saturday",
+			schedule: Schedule{Windows: []ScheduleWindow{{Days: []string{"sat", "sun"}, Start: "00:00", End: "24:00"}}},
+			time:     "2024-06-15T23:59:00Z",
+			want:     true,
+		},
+		{
+			name:     "weekend only on monday",
+			schedule: Schedule{Windows: []ScheduleWindow{{Days: []string{"sat", "sun"}, Start: "00:00", End: "24:00"}}},
+			time:     "2024-06-17T12:00:00Z",
+		},
+		{
+			name:     "overnight window after midnight belongs to previous day",
+			schedule: Schedule{Windows: []ScheduleWindow{{Days: []string{"fri"}, Start: "22:00", End: "02:00"}}},
+			time:     "2024-06-15T01:00:00Z",
+			want:     true,
+		},
+		{
+			name:     "overnight window does not start on wrong day",
+			schedule: Schedule{Windows: []ScheduleWindow{{Days: []string{"fri"}, Start: "22:00", End: "02:00"}}},
+			time:     "2024-06-15T23:00:00Z",
+		},
+		{
+			name:     "match day date",
+			schedule: Schedule{Windows: []ScheduleWindow{{Dates: []string{"2024-06-15"}, Start: "17:00", End: "23:00"}}},
+			time:     "2024-06-15T20:00:00Z",
+			want:     true,
+		},
+		{
+			name:     "timezone shifts window",
+			schedule: Schedule{Timezone: "Europe/Moscow", Windows: []ScheduleWindow{{Start: "10:00", End: "11:00"}}},
+			time:     "2024-06-15T07:30:00Z",
+			want:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compiled, err := tt.schedule.compile()
+			if err != nil {
+				t.Fatalf("compile() error = %v", err)
+			}
+
+			if got := compiled.active(at(tt.time)); got != tt.want {
+				t.Errorf("active(%s) = %v, want %v", tt.time, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule Schedule
+	}{
+		{name: "no windows", schedule: Schedule{}},
+		{name: "unknown timezone", schedule: Schedule{Timezone: "Mars/Olympus", Windows: []ScheduleWindow{{Start: "10:00", End: "11:00"}}}},
+		{name: "unknown day", schedule: Schedule{Windows: []ScheduleWindow{{Days: []string{"funday"}, Start: "10:00", End: "11:00"}}}},
+		{name: "invalid date", schedule: Schedule{Windows: []ScheduleWindow{{Dates: []string{"2024-13-01"}, Start: "10:00", End: "11:00"}}}},
+		{name: "invalid time", schedule: Schedule{Windows: []ScheduleWindow{{Start: "25:00", End: "26:00"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.schedule.compile(); err == nil {
+				t.Error("compile() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestScheduledZoneFollowsClock(t *testing.T) {
+	clk := clock.NewManual(time.Date(2024, 6, 15, 9, 0, 0, 0, time.UTC))
+
+	park := NewMultiPolygon("park", [][][]Point{{square(4, 4, 2)}}, false)
+	zone, err := NewScheduledZone(park, &Schedule{Windows: []ScheduleWindow{{Start: "10:00", End: "18:00"}}}, clk)
+	if err != nil {
+		t.Fatalf("NewScheduledZone() error = %v", err)
+	}
+
+	allowed := NewMultiPolygon("city", [][][]Point{{square(0, 0, 10)}}, true)
+	// индекс строится, пока зона не действует, и должен найти ее после начала окна
+	disabled := []PolygonChecker{NewZoneIndex([]PolygonChecker{zone}, DefaultIndexCellSize)}
+	point := Point{Lat: 5, Lng: 5}
+
+	if !CheckPointIsAllowed(point, allowed, disabled) || zone.Rings() != nil {
+		t.Error("zone restricts point before its window")
+	}
+
+	if zone.Bounds() != park.Bounds() {
+		t.Errorf("Bounds() = %v, want %v", zone.Bounds(), park.Bounds())
+	}
+
+	clk.Set(time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC))
+	if CheckPointIsAllowed(point, allowed, disabled) || len(zone.Rings()) != 1 {
+		t.Error("zone does not restrict point during its window")
+	}
+
+	clk.Set(time.Date(2024, 6, 15, 18, 30, 0, 0, time.UTC))
+	if !CheckPointIsAllowed(point, allowed, disabled) {
+		t.Error("zone restricts point after its window")
+	}
+}
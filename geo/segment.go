@@ -0,0 +1,120 @@
+package geo
+
+import "math"
+
+// SegmentIntersectsZone проверяет, проходит ли отрезок ab через зону:
+// один из концов лежит внутри зоны или отрезок пересекает ее границу
+func SegmentIntersectsZone(a, b Point, zone PolygonChecker) bool {
+	if !segmentBounds(a, b).intersects(zone.Bounds()) {
+		return false
+	}
+
+	if zone.Contains(a) || zone.Contains(b) {
+		return true
+	}
+
+	_, ok := firstCrossing(a, b, zone.Rings())
+
+	return ok
+}
+
+// PathAllowed проверяет, что весь путь из from в to проходит по разрешенной зоне
+// и не пересекает ни одну запрещенную зону
+func PathAllowed(from, to Point, allowedZone PolygonChecker, disabledZones []PolygonChecker) bool {
+	if !CheckPointIsAllowed(from, allowedZone, disabledZones) || !CheckPointIsAllowed(to, allowedZone, disabledZones) {
+		return false
+	}
+
+	_, ok := firstCrossing(from, to, pathRings(from, to, allowedZone, disabledZones))
+
+	return !ok
+}
+
+// ClampPath обрезает путь из разрешенной точки from в to на первой границе, которую он пересекает.
+// Возвращает точку остановки и признак того, что путь был обрезан
+func ClampPath(from, to Point, allowedZone PolygonChecker, disabledZones []PolygonChecker) (Point, bool) {
+	t, crossed := firstCrossing(from, to, pathRings(from, to, allowedZone, disabledZones))
+
+	if !crossed {
+		if CheckPointIsAllowed(to, allowedZone, disabledZones) {
+			return to, false
+		}
+		// граница не пересечена, но конечная точка запрещена - путь лежит на самой границе
+		return from, true
+	}
+
+	stop := Interpolate(from, to, t)
+	// отступаем от границы назад, чтобы точка остановки оказалась внутри разрешенной области
+	stop = stepPast(to, stop)
+
+	if planarDistance(from, stop) > planarDistance(from, to) || !CheckPointIsAllowed(stop, allowedZone, disabledZones) {
+		return from, true
+	}
+
+	return stop, true
+}
+
+// pathRings собирает границы зон, которые может пересечь отрезок
+func pathRings(from, to Point, allowedZone PolygonChecker, disabledZones []PolygonChecker) [][]Point {
+	b := segmentBounds(from, to)
+	rings := allowedZone.Rings()
+
+	for i := range disabledZones {
+		if idx, ok := disabledZones[i].(*ZoneIndex); ok {
+			for _, zone := range idx.QueryBounds(b) {
+				rings = append(rings, zone.Rings()...)
+			}
+			continue
+		}
+
+		if b.intersects(disabledZones[i].Bounds()) {
+			rings = append(rings, disabledZones[i].Rings()...)
+		}
+	}
+
+	return rings
+}
+
+// firstCrossing возвращает наименьший параметр t в [0, 1], при котором отрезок ab пересекает сторону одного из колец
+func firstCrossing(a, b Point, rings [][]Point) (float64, bool) {
+	best := math.Inf(1)
+	found := false
+
+	for _, ring := range rings {
+		for i := range ring {
+			t, ok := segmentIntersection(a, b, ring[i], ring[(i+1)%len(ring)])
+			if ok && t < best {
+				best = t
+				found = true
+			}
+		}
+	}
+
+	return best, found
+}
+
+// segmentIntersection возвращает параметр t точки пересечения отрезков ab и cd вдоль ab
+func segmentIntersection(a, b, c, d Point) (float64, bool) {
+	rx, ry := b.Lng-a.Lng, b.Lat-a.Lat
+	sx, sy := d.Lng-c.Lng, d.Lat-c.Lat
+
+	denom := rx*sy - ry*sx
+	if denom == 0 {
+		// параллельные отрезки, касание вдоль стороны пересечением не считаем
+		return 0, false
+	}
+
+	qx, qy := c.Lng-a.Lng, c.Lat-a.Lat
+	t := (qx*sy - qy*sx) / denom
+	u := (qx*ry - qy*rx) / denom
+
+	if t < 0 || t > 1 || u < 0 || u > 1 {
+		return 0, false
+	}
+
+	return t, true
+}
+
+func segmentBounds(a, b Point) Bounds {
+	return Bounds{Min: a, Max: a}.extend(b)
+}
@@ -0,0 +1,104 @@
+package geo
+
+import (
+	"testing"
+)
+
+func TestClampPath(t *testing.T) {
+	allowed := NewMultiPolygon("city", [][][]Point{{square(0, 0, 10)}}, true)
+	disabled := []PolygonChecker{NewZoneIndex([]PolygonChecker{
+		NewMultiPolygon("park", [][][]Point{{square(4, 4, 2)}}, false),
+	}, DefaultIndexCellSize)}
+
+	tests := []struct {
+		name    string
+		from    Point
+		to      Point
+		want    Point // ожидаемая точка остановки с точностью до отступа от границы
+		clamped bool
+	}{
+		{name: "path inside allowed zone", from: Point{Lat: 1, Lng: 1}, to: Point{Lat: 2, Lng: 2}, want: Point{Lat: 2, Lng: 2}},
+		{name: "path leaves allowed zone", from: Point{Lat: 5, Lng: 8}, to: Point{Lat: 5, Lng: 12}, want: Point{Lat: 5, Lng: 10}, clamped: true},
+		{name: "path ends in disabled zone", from: Point{Lat: 5, Lng: 2}, to: Point{Lat: 5, Lng: 5}, want: Point{Lat: 5, Lng: 4}, clamped: true},
+		{name: "path jumps over disabled zone", from: Point{Lat: 5, Lng: 2}, to: Point{Lat: 5, Lng: 8}, want: Point{Lat: 5, Lng: 4}, clamped: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, clamped := ClampPath(tt.from, tt.to, allowed, disabled)
+			if clamped != tt.clamped {
+				t.Errorf("ClampPath() clamped = %v, want %v", clamped, tt.clamped)
+			}
+
+			if !CheckPointIsAllowed(got, allowed, disabled) {
+				t.Fatalf("ClampPath() = %v, point is not allowed", got)
+			}
+
+			if d := planarDistance(got, tt.want); d > 0.001 {
+				t.Errorf("ClampPath() = %v, want about %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathAllowed(t *testing.T) {
+	allowed := NewMultiPolygon("city", [][][]Point{{square(0, 0, 10)}}, true)
+	disabled := []PolygonChecker{NewMultiPolygon("park", [][][]Point{{square(4, 4, 2)}}, false)}
+
+	if !PathAllowed(Point{Lat: 1, Lng: 1}, Point{Lat: 1, Lng: 9}, allowed, disabled) {
+		t.Error("path along the bottom of the city is not allowed")
+	}
+
+	if PathAllowed(Point{Lat: 5, Lng: 1}, Point{Lat: 5, Lng: 9}, allowed, disabled) {
+		t.Error("path through the park is allowed")
+	}
+
+	if PathAllowed(Point{Lat: 5, Lng: 9}, Point{Lat: 5, Lng: 11}, allowed, disabled) {
+		t.Error("path out of the city is allowed")
+	}
+}
+
+func TestSegmentIntersectsZone(t *testing.T) {
+	zone := NewMultiPolygon("park", [][][]Point{{square(4, 4, 2)}}, false)
+
+	tests := []struct {
+		name string
+		a, b Point
+		want bool
+	}{
+		{name: "crosses zone", a: Point{Lat: 5, Lng: 0}, b: Point{Lat: 5, Lng: 10}, want: true},
+		{name: "ends inside zone", a: Point{Lat: 5, Lng: 0}, b: Point{Lat: 5, Lng: 5}, want: true},
+		{name: "misses zone", a: Point{Lat: 0, Lng: 0}, b: Point{Lat: 0, Lng: 10}, want: false},
+		{name: "bounds overlap only", a: Point{Lat: 2, Lng: 5}, b: Point{Lat: 5, Lng: 2}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SegmentIntersectsZone(tt.a, tt.b, zone); got != tt.want {
+				t.Errorf("SegmentIntersectsZone() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSegmentIntersection(t *testing.T) {
+	tests := []struct {
+		name       string
+		a, b, c, d Point
+		want       float64
+		ok         bool
+	}{
+		{name: "crossing", a: Point{Lat: 0, Lng: 0}, b: Point{Lat: 0, Lng: 4}, c: Point{Lat: -1, Lng: 1}, d: Point{Lat: 1, Lng: 1}, want: 0.25, ok: true},
+		{name: "parallel", a: Point{Lat: 0, Lng: 0}, b: Point{Lat: 0, Lng: 4}, c: Point{Lat: 1, Lng: 0}, d: Point{Lat: 1, Lng: 4}},
+		{name: "apart", a: Point{Lat: 0, Lng: 0}, b: Point{Lat: 0, Lng: 4}, c: Point{Lat: 1, Lng: 5}, d: Point{Lat: 2, Lng: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := segmentIntersection(tt.a, tt.b, tt.c, tt.d)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("segmentIntersection() = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
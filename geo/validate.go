@@ -0,0 +1,212 @@
+package geo
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ValidationReason string
+
+const (
+	ReasonTooFewVertices   ValidationReason = "ring has fewer than three distinct vertices"
+	ReasonDuplicatePoint   ValidationReason = "duplicate consecutive point"
+	ReasonOutOfRange       ValidationReason = "coordinate out of range"
+	ReasonSelfIntersection ValidationReason = "ring is self-intersecting"
+	ReasonOutsideAllowed   ValidationReason = "disabled zone lies outside the allowed zone"
+)
+
+// ValidationError ошибка валидации полигона с указанием зоны, части, кольца и индекса вершины.
+// Для ошибок, не относящихся к конкретной вершине, Index равен -1
+type ValidationError struct {
+	ZoneID string
+	Part   int
+	Ring   int
+	Index  int
+	Reason ValidationReason
+}
+
+func (e *ValidationError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("zone %q part %d ring %d: %s", e.ZoneID, e.Part, e.Ring, e.Reason)
+	}
+
+	return fmt.Sprintf("zone %q part %d ring %d vertex %d: %s", e.ZoneID, e.Part, e.Ring, e.Index, e.Reason)
+}
+
+// ValidationErrors все найденные ошибки валидации
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for i := range e {
+		messages = append(messages, e[i].Error())
+	}
+
+	return "geo: invalid polygons: " + strings.Join(messages, "; ")
+}
+
+// ValidateParts проверяет кольца всех частей зоны и нормализует порядок обхода:
+// внешний контур против часовой стрелки, дыры по часовой стрелке (как в RFC 7946)
+func ValidateParts(zoneID string, parts [][][]Point) ([][][]Point, error) {
+	var errs ValidationErrors
+
+	res := make([][][]Point, len(parts))
+	for i := range parts {
+		res[i] = make([][]Point, len(parts[i]))
+
+		for j := range parts[i] {
+			errs = append(errs, validateRing(zoneID, i, j, parts[i][j])...)
+			// первое кольцо внешний контур
+			res[i][j] = normalizeRing(parts[i][j], j > 0)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return res, nil
+}
+
+func validateRing(zoneID string, part, ring int, points []Point) ValidationErrors {
+	var errs ValidationErrors
+
+	newErr := func(index int, reason ValidationReason) *ValidationError {
+		return &ValidationError{ZoneID: zoneID, Part: part, Ring: ring, Index: index, Reason: reason}
+	}
+
+	distinct := make(map[Point]struct{}, len(points))
+	for i := range points {
+		distinct[points[i]] = struct{}{}
+
+		if points[i].Lat < -90 || points[i].Lat > 90 || points[i].Lng < -180 || points[i].Lng > 180 {
+			errs = append(errs, newErr(i, ReasonOutOfRange))
+		}
+
+		if len(points) > 1 && points[i] == points[(i+1)%len(points)] {
+			errs = append(errs, newErr(i, ReasonDuplicatePoint))
+		}
+	}
+
+	if len(distinct) < 3 {
+		return append(errs, newErr(-1, ReasonTooFewVertices))
+	}
+
+	if i, ok := selfIntersection(points); ok {
+		errs = append(errs, newErr(i, ReasonSelfIntersection))
+	}
+
+	return errs
+}
+
+// selfIntersection ищет пару несмежных сторон кольца, которые пересекаются,
+// и возвращает индекс начальной вершины первой из них.
+// Повторяющиеся подряд вершины пропускаются, иначе стороны вокруг повтора считались бы несмежными
+func selfIntersection(points []Point) (int, bool) {
+	// индексы вершин исходного кольца без повторов подряд
+	var index []int
+	for i := range points {
+		if i == 0 || points[i] != points[index[len(index)-1]] {
+			index = append(index, i)
+		}
+	}
+	for len(index) > 1 && points[index[len(index)-1]] == points[index[0]] {
+		index = index[:len(index)-1]
+	}
+
+	n := len(index)
+	for i := 0; i < n; i++ {
+		a, b := points[index[i]], points[index[(i+1)%n]]
+
+		for j := i + 2; j < n; j++ {
+			// первая и последняя стороны смежные
+			if i == 0 && j == n-1 {
+				continue
+			}
+
+			if _, ok := segmentIntersection(a, b, points[index[j]], points[index[(j+1)%n]]); ok {
+				return index[i], true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// normalizeRing возвращает кольцо с нужным порядком обхода
+func normalizeRing(points []Point, clockwise bool) []Point {
+	if (signedArea(points) < 0) == clockwise {
+		return points
+	}
+
+	res := make([]Point, len(points))
+	for i := range points {
+		res[len(points)-1-i] = points[i]
+	}
+
+	return res
+}
+
+// signedArea ориентированная площадь кольца в координатах (lng, lat), положительная при обходе против часовой стрелки
+func signedArea(points []Point) float64 {
+	var area float64
+	for i := range points {
+		a, b := points[i], points[(i+1)%len(points)]
+		area += a.Lng*b.Lat - b.Lng*a.Lat
+	}
+
+	return area / 2
+}
+
+// ValidateZones проверяет, что каждая запрещенная зона хотя бы частично лежит внутри разрешенной
+func ValidateZones(allowedZone PolygonChecker, disabledZones []PolygonChecker) error {
+	var errs ValidationErrors
+
+	for i := range disabledZones {
+		if !zonesOverlap(allowedZone, disabledZones[i]) {
+			errs = append(errs, &ValidationError{ZoneID: disabledZones[i].ID(), Index: -1, Reason: ReasonOutsideAllowed})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func zonesOverlap(a, b PolygonChecker) bool {
+	if !a.Bounds().intersects(b.Bounds()) {
+		return false
+	}
+
+	aRings := a.Rings()
+	bRings := b.Rings()
+
+	// вершина одной зоны внутри другой
+	for _, ring := range bRings {
+		for i := range ring {
+			if a.Contains(ring[i]) {
+				return true
+			}
+		}
+	}
+	for _, ring := range aRings {
+		for i := range ring {
+			if b.Contains(ring[i]) {
+				return true
+			}
+		}
+	}
+
+	// стороны пересекаются без вершин внутри
+	for _, ring := range bRings {
+		for i := range ring {
+			if _, ok := firstCrossing(ring[i], ring[(i+1)%len(ring)], aRings); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,197 @@
+package geo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateParts(t *testing.T) {
+	type issue struct {
+		Index  int
+		Reason ValidationReason
+	}
+
+	tests := []struct {
+		name string
+		ring []Point
+		want []issue
+	}{
+		{
+			name: "valid ring",
+			ring: square(0, 0, 1),
+		},
+		{
+			name: "self-intersecting bowtie",
+			ring: []Point{{Lat: 0, Lng: 0}, {Lat: 0, Lng: 2}, {Lat: 2, Lng: 0}, {Lat: 2, Lng: 2}},
+			want: []issue{{Index: 1, Reason: ReasonSelfIntersection}},
+		},
+		{
+			name: "fewer than three distinct vertices",
+			ring: []Point{{Lat: 0, Lng: 0}, {Lat: 0, Lng: 1}},
+			want: []issue{{Index: -1, Reason: ReasonTooFewVertices}},
+		},
+		{
+			name: "repeated vertices collapse to a line",
+			ring: []Point{{Lat: 0, Lng: 0}, {Lat: 0, Lng: 1}, {Lat: 0, Lng: 0}, {Lat: 0, Lng: 1}},
+			want: []issue{{Index: -1, Reason: ReasonTooFewVertices}},
+		},
+		{
+			name: "duplicate consecutive point",
+			ring: []Point{{Lat: 0, Lng: 0}, {Lat: 0, Lng: 0}, {Lat: 0, Lng: 1}, {Lat: 1, Lng: 1}},
+			want: []issue{{Index: 0, Reason: ReasonDuplicatePoint}},
+		},
+		{
+			name: "latitude out of range",
+			ring: []Point{{Lat: 91, Lng: 0}, {Lat: 0, Lng: 1}, {Lat: 1, Lng: 1}},
+			want: []issue{{Index: 0, Reason: ReasonOutOfRange}},
+		},
+		{
+			name: "longitude out of range",
+			ring: []Point{{Lat: 0, Lng: 0}, {Lat: 0, Lng: 1}, {Lat: 1, Lng: -181}},
+			want: []issue{{Index: 2, Reason: ReasonOutOfRange}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateParts("zone", [][][]Point{{tt.ring}})
+
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("ValidateParts() error = %v", err)
+				}
+				return
+			}
+
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) {
+				t.Fatalf("ValidateParts() error = %v, want ValidationErrors", err)
+			}
+
+			got := make([]issue, 0, len(verrs))
+			for _, e := range verrs {
+				if e.ZoneID != "zone" || e.Part != 0 || e.Ring != 0 {
+					t.Errorf("error %v points to zone %q part %d ring %d", e, e.ZoneID, e.Part, e.Ring)
+				}
+				got = append(got, issue{Index: e.Index, Reason: e.Reason})
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateParts() errors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePartsReportsRingPosition(t *testing.T) {
+	hole := []Point{{Lat: 2, Lng: 2}, {Lat: 2, Lng: 2}, {Lat: 2, Lng: 3}, {Lat: 3, Lng: 3}}
+
+	_, err := ValidateParts("park", [][][]Point{{square(20, 20, 1)}, {square(0, 0, 10), hole}})
+
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 1 {
+		t.Fatalf("ValidateParts() error = %v, want one validation error", err)
+	}
+
+	want := &ValidationError{ZoneID: "park", Part: 1, Ring: 1, Index: 0, Reason: ReasonDuplicatePoint}
+	if !reflect.DeepEqual(verrs[0], want) {
+		t.Errorf("ValidateParts() error = %+v, want %+v", verrs[0], want)
+	}
+
+	if got, want := verrs[0].Error(), `zone "park" part 1 ring 1 vertex 0: duplicate consecutive point`; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestValidatePartsNormalizesWinding(t *testing.T) {
+	clockwise := []Point{{Lat: 0, Lng: 0}, {Lat: 10, Lng: 0}, {Lat: 10, Lng: 10}, {Lat: 0, Lng: 10}}
+	counterClockwise := square(4, 4, 2)
+
+	parts, err := ValidateParts("zone", [][][]Point{{clockwise, counterClockwise}})
+	if err != nil {
+		t.Fatalf("ValidateParts() error = %v", err)
+	}
+
+	if area := signedArea(parts[0][0]); area <= 0 {
+		t.Errorf("outer ring signed area = %v, want counterclockwise", area)
+	}
+
+	if area := signedArea(parts[0][1]); area >= 0 {
+		t.Errorf("hole signed area = %v, want clockwise", area)
+	}
+
+	// уже правильно ориентированное кольцо не меняется
+	parts, err = ValidateParts("zone", [][][]Point{{counterClockwise}})
+	if err != nil {
+		t.Fatalf("ValidateParts() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(parts[0][0], counterClockwise) {
+		t.Errorf("outer ring = %v, want unchanged %v", parts[0][0], counterClockwise)
+	}
+}
+
+func TestValidateZones(t *testing.T) {
+	allowed := NewMultiPolygon("city", [][][]Point{{square(0, 0, 10)}}, true)
+
+	tests := []struct {
+		name    string
+		zone    []Point
+		outside bool
+	}{
+		{name: "inside", zone: square(4, 4, 2)},
+		{name: "partially inside", zone: square(9, 9, 2)},
+		// полоса пересекает город насквозь, но ни одна вершина не лежит внутри другой зоны
+		{name: "crossing without vertices inside", zone: []Point{{Lat: 4, Lng: -1}, {Lat: 4, Lng: 11}, {Lat: 6, Lng: 11}, {Lat: 6, Lng: -1}}},
+		{name: "outside", zone: square(20, 20, 2), outside: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			disabled := NewMultiPolygon(tt.name, [][][]Point{{tt.zone}}, false)
+
+			err := ValidateZones(allowed, []PolygonChecker{disabled})
+			if !tt.outside {
+				if err != nil {
+					t.Errorf("ValidateZones() error = %v", err)
+				}
+				return
+			}
+
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) || len(verrs) != 1 {
+				t.Fatalf("ValidateZones() error = %v, want one validation error", err)
+			}
+
+			if verrs[0].ZoneID != tt.name || verrs[0].Reason != ReasonOutsideAllowed || verrs[0].Index != -1 {
+				t.Errorf("ValidateZones() error = %+v", verrs[0])
+			}
+		})
+	}
+}
+
+func TestBuildZonesCollectsAllValidationErrors(t *testing.T) {
+	specs := []ZoneSpec{
+		{ID: "city", Allowed: true, Geometry: Geometry{Type: GeometryPolygon, Coordinates: []byte(`[[[0,0],[10,0],[10,10],[0,10],[0,0]]]`)}},
+		{ID: "bowtie", Geometry: Geometry{Type: GeometryPolygon, Coordinates: []byte(`[[[1,1],[3,1],[1,3],[3,3],[1,1]]]`)}},
+		{ID: "line", Geometry: Geometry{Type: GeometryPolygon, Coordinates: []byte(`[[[1,1],[2,2],[1,1]]]`)}},
+	}
+
+	_, _, err := BuildZones(specs, nil)
+
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("BuildZones() error = %v, want ValidationErrors", err)
+	}
+
+	zones := make(map[string]ValidationReason)
+	for _, e := range verrs {
+		zones[e.ZoneID] = e.Reason
+	}
+
+	want := map[string]ValidationReason{"bowtie": ReasonSelfIntersection, "line": ReasonTooFewVertices}
+	if !reflect.DeepEqual(zones, want) {
+		t.Errorf("BuildZones() errors = %v, want %v", zones, want)
+	}
+}
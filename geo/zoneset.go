@@ -0,0 +1,178 @@
+package geo
+
+import (
+	"fmt"
+	"github.com/GoGerman/geo-task/clock"
+	"sync/atomic"
+)
+
+// ZoneSet неизменяемый набор зон, с которым работают сервисы
+type ZoneSet struct {
+	Specs    []ZoneSpec       // описания зон, из которых построен набор
+	Allowed  PolygonChecker   // разрешенная зона
+	Disabled []PolygonChecker // запрещенные зоны, проверяются через сеточный индекс
+
+	attributed []AttributedZone
+	byID       map[string]PolygonChecker
+	scheduled  []*ScheduledZone
+	clock      clock.Clock
+	geoJSON    []byte
+	etag       string
+}
+
+// NewZoneSet строит набор зон по описаниям, время для зон с расписанием берется из clk
+func NewZoneSet(specs []ZoneSpec, clk clock.Clock) (*ZoneSet, error) {
+	allowedZone, disabledZones, err := BuildZones(specs, clk)
+	if err != nil {
+		return nil, err
+	}
+
+	attributed, err := buildAttributedZones(specs, clk)
+	if err != nil {
+		return nil, err
+	}
+
+	byID, err := buildZonesByID(specs)
+	if err != nil {
+		return nil, err
+	}
+
+	var scheduled []*ScheduledZone
+	for i := range disabledZones {
+		if zone, ok := disabledZones[i].(*ScheduledZone); ok {
+			scheduled = append(scheduled, zone)
+		}
+	}
+	for i := range attributed {
+		if zone, ok := attributed[i].Zone.(*ScheduledZone); ok {
+			scheduled = append(scheduled, zone)
+		}
+	}
+
+	set := &ZoneSet{
+		Specs:      specs,
+		Allowed:    allowedZone,
+		Disabled:   []PolygonChecker{NewZoneIndex(disabledZones, DefaultIndexCellSize)},
+		attributed: attributed,
+		byID:       byID,
+		scheduled:  scheduled,
+		clock:      clk,
+	}
+
+	// без расписаний GeoJSON для фронтенда не меняется и сериализуется один раз при построении набора
+	if len(scheduled) == 0 {
+		set.geoJSON, err = MarshalFeatureCollection(specs, nil)
+		if err != nil {
+			return nil, err
+		}
+		set.etag = etagOf(set.geoJSON)
+	}
+
+	return set, nil
+}
+
+// GeoJSON возвращает набор зон в виде GeoJSON FeatureCollection и его ETag.
+// Для зон с расписанием добавляется признак active, поэтому ETag меняется и при смене окна расписания
+func (s *ZoneSet) GeoJSON() ([]byte, string, error) {
+	if len(s.scheduled) == 0 {
+		return s.geoJSON, s.etag, nil
+	}
+
+	now := s.clock.Now()
+	active := make(map[string]bool, len(s.scheduled))
+	for i := range s.scheduled {
+		active[s.scheduled[i].ID()] = s.scheduled[i].ActiveAt(now)
+	}
+
+	data, err := MarshalFeatureCollection(s.Specs, active)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, etagOf(data), nil
+}
+
+// AttributesAt возвращает атрибуты зон, в которые попадает точка
+func (s *ZoneSet) AttributesAt(point Point) Attributes {
+	return attributesAt(point, s.attributed)
+}
+
+// AttributedZonesAt возвращает зоны с атрибутами, в которые попадает точка, в порядке описаний
+func (s *ZoneSet) AttributedZonesAt(point Point) []AttributedZone {
+	return attributedZonesAt(point, s.attributed)
+}
+
+// Zone возвращает зону id без учета расписания, например чтобы проверить границы рабочей зоны курьера
+func (s *ZoneSet) Zone(id string) (PolygonChecker, bool) {
+	zone, ok := s.byID[id]
+
+	return zone, ok
+}
+
+// ZoneIDsAt возвращает id всех зон набора, в которые попадает точка, в порядке описаний.
+// В отличие от All каждая зона проверяется под своим id: разрешенные зоны не объединяются,
+// а зоны, которые только задают атрибуты, тоже учитываются
+func (s *ZoneSet) ZoneIDsAt(point Point) []string {
+	var ids []string
+	for i := range s.Specs {
+		zone, ok := s.byID[s.Specs[i].ID]
+		if ok && zone.Contains(point) {
+			ids = append(ids, s.Specs[i].ID)
+		}
+	}
+
+	return ids
+}
+
+// buildZonesByID строит по отдельной зоне на каждое описание, включая разрешенные зоны, которые в наборе объединяются
+func buildZonesByID(specs []ZoneSpec) (map[string]PolygonChecker, error) {
+	zones := make(map[string]PolygonChecker, len(specs))
+
+	for i := range specs {
+		parts, err := specs[i].Geometry.parts()
+		if err != nil {
+			return nil, fmt.Errorf("geojson: zone %q: %w", specs[i].ID, err)
+		}
+
+		parts, err = ValidateParts(specs[i].ID, parts)
+		if err != nil {
+			return nil, err
+		}
+
+		zones[specs[i].ID] = NewMultiPolygon(specs[i].ID, parts, specs[i].Allowed)
+	}
+
+	return zones, nil
+}
+
+// All возвращает разрешенную и запрещенные зоны одним списком
+func (s *ZoneSet) All() []PolygonChecker {
+	return append([]PolygonChecker{s.Allowed}, s.Disabled...)
+}
+
+// ZoneProvider источник актуального набора зон
+type ZoneProvider interface {
+	Zones() *ZoneSet
+}
+
+// ZoneStore хранит текущий набор зон и атомарно подменяет его при изменениях,
+// поэтому чтение на горячем пути не требует блокировок
+type ZoneStore struct {
+	current atomic.Pointer[ZoneSet]
+}
+
+func NewZoneStore(set *ZoneSet) *ZoneStore {
+	s := &ZoneStore{}
+	s.current.Store(set)
+
+	return s
+}
+
+func (s *ZoneStore) Zones() *ZoneSet {
+	return s.current.Load()
+}
+
+// Swap заменяет текущий набор зон
+func (s *ZoneStore) Swap(set *ZoneSet) {
+	s.current.Store(set)
+}
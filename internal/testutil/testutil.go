@@ -0,0 +1,125 @@
+// Package testutil общая подготовка окружения для тестов сервисов:
+// redis в памяти, управляемые часы, зоны города и сервис курьеров
+package testutil
+
+import (
+	"context"
+	"github.com/GoGerman/geo-task/clock"
+	"github.com/GoGerman/geo-task/geo"
+	"github.com/GoGerman/geo-task/module/courier/events"
+	"github.com/GoGerman/geo-task/module/courier/models"
+	cservice "github.com/GoGerman/geo-task/module/courier/service"
+	cstorage "github.com/GoGerman/geo-task/module/courier/storage"
+	"github.com/GoGerman/geo-task/random"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"testing"
+	"time"
+)
+
+// Start время, с которого идут часы теста
+var Start = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// CityZones разрешенная зона вокруг точки по умолчанию, в которой создаются курьеры
+var CityZones = []geo.ZoneSpec{
+	{ID: "city", Allowed: true, Geometry: geo.Geometry{Type: geo.GeometryPolygon, Coordinates: []byte(`[[[30.2,59.85],[30.5,59.85],[30.5,60.0],[30.2,60.0],[30.2,59.85]]]`)}},
+}
+
+// Env окружение теста, все сервисы окружения работают с одним redis и одними часами
+type Env struct {
+	Redis  *miniredis.Miniredis
+	Client *redis.Client
+	Clock  *clock.Manual
+	Zones  *geo.ZoneStore
+}
+
+// NewEnv запускает redis в памяти на время теста и строит зоны specs, без specs - CityZones
+func NewEnv(t testing.TB, specs ...geo.ZoneSpec) *Env {
+	t.Helper()
+
+	if len(specs) == 0 {
+		specs = CityZones
+	}
+
+	mr := miniredis.RunT(t)
+	// время redis совпадает с часами теста, иначе ключи с EXPIREAT на время теста сразу истекают
+	mr.SetTime(Start)
+	rclient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rclient.Close() })
+
+	clk := clock.NewManual(Start)
+	set, err := geo.NewZoneSet(specs, clk)
+	if err != nil {
+		t.Fatalf("NewZoneSet() error = %v", err)
+	}
+
+	return &Env{
+		Redis:  mr,
+		Client: rclient,
+		Clock:  clk,
+		Zones:  geo.NewZoneStore(set),
+	}
+}
+
+// Advance переводит часы теста и время redis на d вперед, ключи с истекшим TTL удаляются
+func (e *Env) Advance(d time.Duration) {
+	e.Clock.Add(d)
+	e.Redis.SetTime(e.Clock.Now())
+	e.Redis.FastForward(d)
+}
+
+// CourierOptions зависимости сервиса курьеров: nil зоны - зоны окружения, nil получатели - события отбрасываются
+type CourierOptions struct {
+	Zones      geo.ZoneProvider
+	Geofence   events.GeofenceSink
+	Suspicious events.SuspiciousSink
+}
+
+// Couriers создает сервис курьеров окружения
+func (e *Env) Couriers(opts CourierOptions) cservice.Courierer {
+	if opts.Zones == nil {
+		opts.Zones = e.Zones
+	}
+	if opts.Geofence == nil {
+		opts.Geofence = events.NopSink{}
+	}
+	if opts.Suspicious == nil {
+		opts.Suspicious = events.NopSuspiciousSink{}
+	}
+
+	return cservice.NewCourierService(
+		e.CourierStorage(),
+		cstorage.NewTrackStorage(e.Client),
+		cstorage.NewPresenceStorage(e.Client),
+		opts.Zones,
+		opts.Geofence,
+		opts.Suspicious,
+		e.Clock,
+		random.New(1),
+		cservice.DefaultMaxSpeed,
+		cservice.DefaultPresenceTTL,
+	)
+}
+
+// CourierStorage возвращает хранилище курьеров окружения
+func (e *Env) CourierStorage() cstorage.CourierStorager {
+	return cstorage.NewCourierStorage(e.Client)
+}
+
+// IdleCourier создает курьера и выводит его на линию
+func IdleCourier(t testing.TB, couriers cservice.Courierer, vehicle models.Vehicle) *models.Courier {
+	t.Helper()
+
+	ctx := context.Background()
+	courier, err := couriers.CreateCourier(ctx, vehicle)
+	if err != nil {
+		t.Fatalf("CreateCourier() error = %v", err)
+	}
+
+	courier, err = couriers.Transition(ctx, courier.ID, models.CourierIdle, 0)
+	if err != nil {
+		t.Fatalf("Transition() error = %v", err)
+	}
+
+	return courier
+}
@@ -0,0 +1,234 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/GoGerman/geo-task/module/courier/models"
+	"github.com/GoGerman/geo-task/module/courier/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultNearbyLimit количество ближайших курьеров по умолчанию
+const DefaultNearbyLimit = 10
+
+// CreateRequest запрос на создание курьера, тело запроса необязательно
+type CreateRequest struct {
+	Vehicle models.Vehicle `json:"vehicle"` // foot, bicycle или car, по умолчанию foot
+}
+
+// StateRequest запрос на смену состояния курьера
+type StateRequest struct {
+	State models.CourierState `json:"state" binding:"required"` // offline или idle
+}
+
+type CourierController struct {
+	courierService service.Courierer
+}
+
+func NewCourierController(courierService service.Courierer) *CourierController {
+	return &CourierController{courierService: courierService}
+}
+
+func (c *CourierController) List(ctx *gin.Context) {
+	couriers, err := c.courierService.ListCouriers(ctx)
+	if err != nil {
+		c.error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, couriers)
+}
+
+func (c *CourierController) Get(ctx *gin.Context) {
+	courier, err := c.courierService.GetCourier(ctx, ctx.Param("id"))
+	if err != nil {
+		c.error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, courier)
+}
+
+func (c *CourierController) Create(ctx *gin.Context) {
+	var req CreateRequest
+
+	if ctx.Request.ContentLength != 0 {
+		err := ctx.ShouldBindJSON(&req)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	courier, err := c.courierService.CreateCourier(ctx, req.Vehicle)
+	if err != nil {
+		c.error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, courier)
+}
+
+func (c *CourierController) Delete(ctx *gin.Context) {
+	err := c.courierService.DeleteCourier(ctx, ctx.Param("id"))
+	if err != nil {
+		c.error(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// SetState выводит курьера на линию или снимает с нее, недопустимый переход возвращает 409.
+// Состояния, связанные с заказами, меняются только при получении и доставке заказов, поэтому через API недоступны
+func (c *CourierController) SetState(ctx *gin.Context) {
+	var req StateRequest
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.State != models.CourierOffline && req.State != models.CourierIdle {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("state must be %s or %s, got %q", models.CourierOffline, models.CourierIdle, req.State)})
+		return
+	}
+
+	courier, err := c.courierService.Transition(ctx, ctx.Param("id"), req.State, 0)
+	if err != nil {
+		c.error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, courier)
+}
+
+// States отдает историю смены состояний курьера
+func (c *CourierController) States(ctx *gin.Context) {
+	transitions, err := c.courierService.Transitions(ctx, ctx.Param("id"))
+	if err != nil {
+		c.error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transitions)
+}
+
+// Nearby отдает курьеров, ближайших к точке lat, lng, отсортированных по расстоянию.
+// radius в метрах ограничивает поиск, limit - количество курьеров
+func (c *CourierController) Nearby(ctx *gin.Context) {
+	var point models.Point
+	var radius float64
+	var err error
+
+	point.Lat, err = strconv.ParseFloat(ctx.Query("lat"), 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid lat: " + err.Error()})
+		return
+	}
+
+	point.Lng, err = strconv.ParseFloat(ctx.Query("lng"), 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid lng: " + err.Error()})
+		return
+	}
+
+	if v := ctx.Query("radius"); v != "" {
+		radius, err = strconv.ParseFloat(v, 64)
+		if err != nil || radius < 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid radius " + v})
+			return
+		}
+	}
+
+	limit := DefaultNearbyLimit
+	if v := ctx.Query("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit " + v})
+			return
+		}
+	}
+
+	couriers, err := c.courierService.NearbyCouriers(ctx, point, radius, limit)
+	if err != nil {
+		c.error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, couriers)
+}
+
+// Track отдает историю перемещений курьера за период from - to (RFC3339) в формате GeoJSON или GPX
+func (c *CourierController) Track(ctx *gin.Context) {
+	var from, to time.Time
+	var err error
+
+	if v := ctx.Query("from"); v != "" {
+		from, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: " + err.Error()})
+			return
+		}
+	}
+
+	if v := ctx.Query("to"); v != "" {
+		to, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: " + err.Error()})
+			return
+		}
+	}
+
+	format := ctx.DefaultQuery("format", TrackFormatGeoJSON)
+	if format != TrackFormatGeoJSON && format != TrackFormatGPX {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "unsupported format " + format})
+		return
+	}
+
+	id := ctx.Param("id")
+
+	// трек доступен и для удаленного курьера, пока не истек срок его хранения
+	points, err := c.courierService.Track(ctx, id, from, to)
+	if err != nil {
+		c.error(ctx, err)
+		return
+	}
+
+	if format == TrackFormatGPX {
+		data, err := marshalTrackGPX(id, points)
+		if err != nil {
+			c.error(ctx, err)
+			return
+		}
+
+		ctx.Data(http.StatusOK, "application/gpx+xml", data)
+		return
+	}
+
+	data, err := marshalTrackGeoJSON(id, points)
+	if err != nil {
+		c.error(ctx, err)
+		return
+	}
+
+	ctx.Data(http.StatusOK, "application/geo+json", data)
+}
+
+func (c *CourierController) error(ctx *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrCourierNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidTransition):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrUnknownState), errors.Is(err, service.ErrUnknownVehicle):
+		status = http.StatusBadRequest
+	}
+
+	ctx.JSON(status, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"github.com/GoGerman/geo-task/module/courier/models"
+	"time"
+)
+
+// форматы выгрузки трека курьера
+const (
+	TrackFormatGeoJSON = "geojson"
+	TrackFormatGPX     = "gpx"
+)
+
+type trackFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   *trackLineString       `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type trackLineString struct {
+	Type        string       `json:"type"`
+	Coordinates [][2]float64 `json:"coordinates"`
+}
+
+// marshalTrackGeoJSON сериализует трек в GeoJSON Feature с геометрией LineString.
+// Время точек передается в свойстве times в том же порядке, что и координаты.
+// LineString требует минимум две точки, для более короткого трека геометрия null
+func marshalTrackGeoJSON(courierID string, points []models.TrackPoint) ([]byte, error) {
+	times := make([]time.Time, 0, len(points))
+	coordinates := make([][2]float64, 0, len(points))
+	for i := range points {
+		times = append(times, points[i].Timestamp)
+		// координаты в GeoJSON задаются в порядке [lng, lat]
+		coordinates = append(coordinates, [2]float64{points[i].Location.Lng, points[i].Location.Lat})
+	}
+
+	feature := trackFeature{
+		Type: "Feature",
+		Properties: map[string]interface{}{
+			"courier_id": courierID,
+			"times":      times,
+		},
+	}
+
+	if len(coordinates) >= 2 {
+		feature.Geometry = &trackLineString{Type: "LineString", Coordinates: coordinates}
+	}
+
+	return json.Marshal(feature)
+}
+
+type gpx struct {
+	XMLName xml.Name `xml:"gpx"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Version string   `xml:"version,attr"`
+	Creator string   `xml:"creator,attr"`
+	Track   gpxTrack `xml:"trk"`
+}
+
+type gpxTrack struct {
+	Name    string          `xml:"name"`
+	Segment gpxTrackSegment `xml:"trkseg"`
+}
+
+type gpxTrackSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxPoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lng  float64 `xml:"lon,attr"`
+	Time string  `xml:"time"`
+}
+
+// marshalTrackGPX сериализует трек в GPX 1.1 с одним треком и одним сегментом
+func marshalTrackGPX(courierID string, points []models.TrackPoint) ([]byte, error) {
+	doc := gpx{
+		Xmlns:   "http://www.topografix.com/GPX/1/1",
+		Version: "1.1",
+		Creator: "geo-task",
+		Track: gpxTrack{
+			Name: "courier " + courierID,
+		},
+	}
+
+	doc.Track.Segment.Points = make([]gpxPoint, 0, len(points))
+	for i := range points {
+		doc.Track.Segment.Points = append(doc.Track.Segment.Points, gpxPoint{
+			Lat:  points[i].Location.Lat,
+			Lng:  points[i].Location.Lng,
+			Time: points[i].Timestamp.UTC().Format(time.RFC3339),
+		})
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,58 @@
+package events
+
+import (
+	"context"
+	"github.com/GoGerman/geo-task/module/courier/models"
+	"log"
+)
+
+// DefaultAsyncSinkSize размер буфера событий AsyncSink по умолчанию
+const DefaultAsyncSinkSize = 1024
+
+// AsyncSink отправляет события получателю next в отдельной горутине, поэтому медленный получатель,
+// например вебхук, не задерживает перемещение курьера. Если буфер заполнен - событие отбрасывается
+type AsyncSink struct {
+	next GeofenceSink
+	ch   chan models.GeofenceEvent
+	done chan struct{}
+}
+
+// NewAsyncSink запускает отправку событий получателю next, size - размер буфера событий
+func NewAsyncSink(next GeofenceSink, size int) *AsyncSink {
+	s := &AsyncSink{
+		next: next,
+		ch:   make(chan models.GeofenceEvent, size),
+		done: make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+func (s *AsyncSink) run() {
+	defer close(s.done)
+
+	// контекст запроса, в котором событие опубликовано, к моменту отправки может быть уже отменен
+	for event := range s.ch {
+		err := s.next.Publish(context.Background(), event)
+		if err != nil {
+			log.Printf("error while publishing geofence event: %v", err)
+		}
+	}
+}
+
+func (s *AsyncSink) Publish(ctx context.Context, event models.GeofenceEvent) error {
+	select {
+	case s.ch <- event:
+		return nil
+	default:
+		return ErrSinkFull
+	}
+}
+
+// Close отправляет события из буфера и останавливает отправку, после Close публиковать события нельзя
+func (s *AsyncSink) Close() {
+	close(s.ch)
+	<-s.done
+}
@@ -0,0 +1,78 @@
+package events
+
+import (
+	"context"
+	"github.com/GoGerman/geo-task/module/courier/models"
+	"sync"
+	"testing"
+)
+
+// blockingSink принимает события только после закрытия release
+type blockingSink struct {
+	release chan struct{}
+
+	mu     sync.Mutex
+	events []models.GeofenceEvent
+}
+
+func (s *blockingSink) Publish(ctx context.Context, event models.GeofenceEvent) error {
+	<-s.release
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, event)
+	return nil
+}
+
+func TestAsyncSinkDoesNotWaitForReceiver(t *testing.T) {
+	next := &blockingSink{release: make(chan struct{})}
+	sink := NewAsyncSink(next, 2)
+
+	// первое событие забирает горутина отправки и ждет получателя, еще два помещаются в буфер
+	published := 0
+	for i := 0; i < 10; i++ {
+		err := sink.Publish(context.Background(), models.GeofenceEvent{ZoneID: "zone"})
+		if err == ErrSinkFull {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+		published++
+	}
+
+	if published < 2 || published > 3 {
+		t.Errorf("published %d events, want 2 or 3", published)
+	}
+
+	close(next.release)
+	sink.Close()
+
+	if len(next.events) != published {
+		t.Errorf("receiver got %d events, want %d", len(next.events), published)
+	}
+}
+
+func TestAsyncSinkIgnoresCanceledContext(t *testing.T) {
+	next := NewChanSink(1)
+	sink := NewAsyncSink(next, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := sink.Publish(ctx, models.GeofenceEvent{ZoneID: "zone"})
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	cancel()
+
+	sink.Close()
+
+	select {
+	case event := <-next.Events():
+		if event.ZoneID != "zone" {
+			t.Errorf("ZoneID = %q, want zone", event.ZoneID)
+		}
+	default:
+		t.Error("event was not delivered after request context was canceled")
+	}
+}
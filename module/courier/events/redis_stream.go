@@ -0,0 +1,40 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/GoGerman/geo-task/module/courier/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const GeofenceStreamKey = "courier:geofence"
+
+// geofenceStreamMaxLen приблизительное ограничение длины стрима
+const geofenceStreamMaxLen = 10000
+
+// RedisStreamSink записывает события в redis stream
+type RedisStreamSink struct {
+	storage *redis.Client
+}
+
+func NewRedisStreamSink(storage *redis.Client) *RedisStreamSink {
+	return &RedisStreamSink{storage: storage}
+}
+
+func (s *RedisStreamSink) Publish(ctx context.Context, event models.GeofenceEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return s.storage.XAdd(ctx, &redis.XAddArgs{
+		Stream: GeofenceStreamKey,
+		MaxLen: geofenceStreamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"type":    string(event.Type),
+			"zone_id": event.ZoneID,
+			"event":   data,
+		},
+	}).Err()
+}
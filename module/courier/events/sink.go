@@ -0,0 +1,46 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"github.com/GoGerman/geo-task/module/courier/models"
+)
+
+var ErrSinkFull = errors.New("events: sink buffer is full")
+
+// GeofenceSink получатель событий входа и выхода курьеров из зон
+type GeofenceSink interface {
+	Publish(ctx context.Context, event models.GeofenceEvent) error // отправить событие
+}
+
+// ChanSink отправляет события в канал, если канал заполнен - событие отбрасывается
+type ChanSink struct {
+	ch chan models.GeofenceEvent
+}
+
+func NewChanSink(size int) *ChanSink {
+	return &ChanSink{ch: make(chan models.GeofenceEvent, size)}
+}
+
+// Events канал для чтения событий
+func (s *ChanSink) Events() <-chan models.GeofenceEvent {
+	return s.ch
+}
+
+func (s *ChanSink) Publish(ctx context.Context, event models.GeofenceEvent) error {
+	select {
+	case s.ch <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return ErrSinkFull
+	}
+}
+
+// NopSink отбрасывает все события
+type NopSink struct{}
+
+func (NopSink) Publish(ctx context.Context, event models.GeofenceEvent) error {
+	return nil
+}
@@ -0,0 +1,52 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/GoGerman/geo-task/module/courier/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const SuspiciousStreamKey = "courier:suspicious"
+
+// suspiciousStreamMaxLen приблизительное ограничение длины стрима
+const suspiciousStreamMaxLen = 10000
+
+// SuspiciousSink получатель подозрительных событий, например перемещений с невозможной скоростью
+type SuspiciousSink interface {
+	Publish(ctx context.Context, event models.SuspiciousEvent) error // отправить событие
+}
+
+// RedisSuspiciousSink записывает подозрительные события в redis stream
+type RedisSuspiciousSink struct {
+	storage *redis.Client
+}
+
+func NewRedisSuspiciousSink(storage *redis.Client) *RedisSuspiciousSink {
+	return &RedisSuspiciousSink{storage: storage}
+}
+
+func (s *RedisSuspiciousSink) Publish(ctx context.Context, event models.SuspiciousEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return s.storage.XAdd(ctx, &redis.XAddArgs{
+		Stream: SuspiciousStreamKey,
+		MaxLen: suspiciousStreamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"type":       string(event.Type),
+			"courier_id": event.CourierID,
+			"event":      data,
+		},
+	}).Err()
+}
+
+// NopSuspiciousSink отбрасывает все события
+type NopSuspiciousSink struct{}
+
+func (NopSuspiciousSink) Publish(ctx context.Context, event models.SuspiciousEvent) error {
+	return nil
+}
@@ -1,10 +1,42 @@
 package models
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Courier struct {
-	Score    int   `json:"score"`
-	Location Point `json:"location"`
+	ID       string `json:"id"`
+	Score    int    `json:"score"`
+	Location Point  `json:"location"`
+	// время последнего перемещения, по нему проверяется скорость следующего перемещения
+	UpdatedAt time.Time    `json:"updated_at"`
+	State     CourierState `json:"state"`
+	// время последнего перехода между состояниями
+	StateChangedAt time.Time `json:"state_changed_at"`
+	Vehicle        Vehicle   `json:"vehicle"`
+	// заказы, которые курьер забрал и еще не доставил
+	Orders []int64 `json:"orders"`
+	// запас расстояния в метрах сверх максимальной скорости, тратится на быстрые перемещения
+	SpeedAllowance float64 `json:"speed_allowance"`
+	// число перемещений подряд быстрее допустимой скорости
+	SpeedViolations int `json:"speed_violations"`
+}
+
+// FreeCapacity возвращает, сколько еще заказов может забрать курьер
+func (c Courier) FreeCapacity() int {
+	return c.Vehicle.Capacity() - len(c.Orders)
+}
+
+// Carries проверяет, везет ли курьер заказ orderID
+func (c Courier) Carries(orderID int64) bool {
+	for i := range c.Orders {
+		if c.Orders[i] == orderID {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (c Courier) MarshalBinary() ([]byte, error) {
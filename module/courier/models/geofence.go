@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type GeofenceEventType string
+
+const (
+	GeofenceEnter     GeofenceEventType = "enter"     // курьер вошел в зону
+	GeofenceExit      GeofenceEventType = "exit"      // курьер покинул зону
+	GeofenceViolation GeofenceEventType = "violation" // курьер пытался войти в запрещенную зону и остановлен на ее границе
+)
+
+// GeofenceEvent событие входа курьера в зону, выхода из нее или попытки войти в запрещенную зону
+type GeofenceEvent struct {
+	Type      GeofenceEventType `json:"type"`
+	ZoneID    string            `json:"zone_id"`
+	Courier   Courier           `json:"courier"`
+	Location  Point             `json:"location"` // положение курьера, для violation - точка, в которую курьер пытался попасть
+	Timestamp time.Time         `json:"timestamp"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// CourierState состояние курьера
+type CourierState string
+
+const (
+	CourierOffline    CourierState = "offline"    // курьер не работает
+	CourierIdle       CourierState = "idle"       // курьер свободен и видит новые заказы
+	CourierAssigned   CourierState = "assigned"   // курьеру назначен заказ
+	CourierPickingUp  CourierState = "picking_up" // курьер забирает заказ
+	CourierDelivering CourierState = "delivering" // курьер везет заказы
+)
+
+// courierTransitions допустимые переходы между состояниями:
+// offline -> idle -> assigned -> picking_up -> delivering -> idle,
+// свободный курьер может уйти offline, от назначенного заказа можно отказаться,
+// курьер с заказами может по пути забрать еще один, если позволяет вместимость
+var courierTransitions = map[CourierState][]CourierState{
+	CourierOffline:    {CourierIdle},
+	CourierIdle:       {CourierOffline, CourierAssigned},
+	CourierAssigned:   {CourierPickingUp, CourierIdle},
+	CourierPickingUp:  {CourierDelivering},
+	CourierDelivering: {CourierIdle, CourierPickingUp},
+}
+
+// Valid проверяет, что состояние известно
+func (s CourierState) Valid() bool {
+	_, ok := courierTransitions[s]
+
+	return ok
+}
+
+// CanTransitionTo проверяет, допустим ли переход из состояния s в to
+func (s CourierState) CanTransitionTo(to CourierState) bool {
+	for _, state := range courierTransitions[s] {
+		if state == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StateTransition переход курьера из одного состояния в другое
+type StateTransition struct {
+	CourierID string       `json:"courier_id"`
+	From      CourierState `json:"from"`
+	To        CourierState `json:"to"`
+	OrderID   int64        `json:"order_id,omitempty"` // заказ, с которым связан переход
+	Timestamp time.Time    `json:"timestamp"`
+}
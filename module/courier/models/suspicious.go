@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+type SuspiciousEventType string
+
+const (
+	SuspiciousSpeed SuspiciousEventType = "speed" // перемещение быстрее допустимой скорости
+)
+
+type SuspiciousAction string
+
+const (
+	SuspiciousClamped  SuspiciousAction = "clamped"  // перемещение укорочено до допустимого
+	SuspiciousRejected SuspiciousAction = "rejected" // обновление отклонено
+)
+
+// SuspiciousEvent подозрительное обновление положения курьера
+type SuspiciousEvent struct {
+	Type       SuspiciousEventType `json:"type"`
+	Action     SuspiciousAction    `json:"action"`
+	CourierID  string              `json:"courier_id"`
+	From       Point               `json:"from"`
+	To         Point               `json:"to"`         // запрошенная точка
+	Distance   float64             `json:"distance"`   // запрошенное расстояние в метрах
+	Elapsed    float64             `json:"elapsed"`    // секунд с последнего обновления
+	Speed      float64             `json:"speed"`      // скорость в м/с, которую подразумевает обновление
+	Violations int                 `json:"violations"` // нарушений скорости подряд, включая это
+	Timestamp  time.Time           `json:"timestamp"`
+}
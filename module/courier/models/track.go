@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// TrackPoint точка трека курьера.
+// Для положений с устройства дополнительно сохраняются точность, направление, скорость и время устройства
+type TrackPoint struct {
+	Location   Point      `json:"location"`
+	Timestamp  time.Time  `json:"timestamp"`
+	Accuracy   float64    `json:"accuracy,omitempty"`
+	Heading    float64    `json:"heading,omitempty"`
+	Speed      float64    `json:"speed,omitempty"`
+	DeviceTime *time.Time `json:"device_time,omitempty"`
+}
+
+// Position абсолютное положение курьера, полученное с устройства
+type Position struct {
+	Location  Point     `json:"location"`
+	Accuracy  float64   `json:"accuracy"`  // точность в метрах
+	Heading   float64   `json:"heading"`   // направление движения в градусах от севера по часовой стрелке
+	Speed     float64   `json:"speed"`     // скорость в м/с
+	Timestamp time.Time `json:"timestamp"` // время на устройстве
+}
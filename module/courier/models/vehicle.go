@@ -0,0 +1,31 @@
+package models
+
+// Vehicle тип транспорта курьера, от него зависит, сколько заказов курьер везет одновременно
+type Vehicle string
+
+const (
+	VehicleFoot    Vehicle = "foot"    // пеший курьер с сумкой
+	VehicleBicycle Vehicle = "bicycle" // велосипед с коробом
+	VehicleCar     Vehicle = "car"     // автомобиль
+)
+
+// DefaultVehicle транспорт курьера, для которого тип не задан
+const DefaultVehicle = VehicleFoot
+
+var vehicleCapacity = map[Vehicle]int{
+	VehicleFoot:    2,
+	VehicleBicycle: 3,
+	VehicleCar:     6,
+}
+
+// Valid проверяет, что тип транспорта известен
+func (v Vehicle) Valid() bool {
+	_, ok := vehicleCapacity[v]
+
+	return ok
+}
+
+// Capacity возвращает максимальное количество заказов, которые курьер везет одновременно
+func (v Vehicle) Capacity() int {
+	return vehicleCapacity[v]
+}
@@ -4,10 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/GoGerman/geo-task/cache"
+	"github.com/GoGerman/geo-task/clock"
 	"github.com/GoGerman/geo-task/geo"
+	"github.com/GoGerman/geo-task/module/courier/events"
 	"github.com/GoGerman/geo-task/module/courier/models"
 	"github.com/GoGerman/geo-task/module/courier/storage"
+	"github.com/GoGerman/geo-task/random"
+	"log"
 	"math"
+	"strconv"
+	"time"
 )
 
 // Направления движения курьера
@@ -23,71 +30,371 @@ const (
 	DefaultCourierLng = 30.3609
 )
 
+// DefaultMaxSpeed максимальная скорость курьера в м/с, около 108 км/ч
+const DefaultMaxSpeed = 30.0
+
+// SpeedBurstDistance запас в метрах сверх максимальной скорости на погрешность GPS и неравномерные интервалы сообщений.
+// Запас тратится на быстрые перемещения и восстанавливается при движении медленнее максимальной скорости,
+// поэтому частые сообщения не позволяют накопить его больше, чем на одно перемещение
+const SpeedBurstDistance = 100.0
+
+// SustainedViolations число нарушений скорости подряд, начиная с которого они записываются как подозрительные.
+// Единичные скачки координат случаются и у честных курьеров
+const SustainedViolations = 3
+
+// DefaultPresenceTTL время, через которое курьер без heartbeat считается ушедшим
+const DefaultPresenceTTL = time.Minute
+
+var (
+	ErrCourierNotFound   = errors.New("courier not found")
+	ErrInvalidPosition   = errors.New("invalid position")
+	ErrSpeedExceeded     = errors.New("speed limit exceeded")
+	ErrInvalidTransition = errors.New("invalid state transition")
+	ErrUnknownState      = errors.New("unknown courier state")
+	ErrUnknownVehicle    = errors.New("unknown vehicle")
+	ErrCapacityExceeded  = errors.New("courier capacity exceeded")
+	ErrOrderNotCarried   = errors.New("order is not carried by courier")
+)
+
+// InvalidTransitionError переход между состояниями курьера не допускается
+type InvalidTransitionError struct {
+	From models.CourierState
+	To   models.CourierState
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("%s from %s to %s", ErrInvalidTransition, e.From, e.To)
+}
+
+func (e *InvalidTransitionError) Unwrap() error {
+	return ErrInvalidTransition
+}
+
 type Courierer interface {
-	GetCourier(ctx context.Context) (*models.Courier, error)
-	MoveCourier(courier models.Courier, direction, zoom int) error
+	GetCourier(ctx context.Context, id string) (*models.Courier, error)                                          // возвращает курьера по id, курьер вне разрешенной зоны перемещается в нее
+	Exists(ctx context.Context, id string) (bool, error)                                                         // проверяет, что курьер есть, в отличие от GetCourier не меняет его
+	CreateCourier(ctx context.Context, vehicle models.Vehicle) (*models.Courier, error)                          // создает курьера offline с уникальным id в точке по умолчанию, пустой vehicle - пеший курьер
+	ListCouriers(ctx context.Context) ([]models.Courier, error)                                                  // возвращает всех активных курьеров
+	DeleteCourier(ctx context.Context, id string) error                                                          // удаляет курьера
+	MoveCourier(ctx context.Context, id string, direction, zoom int) (*models.Courier, error)                    // перемещает курьера id в направлении direction и возвращает его с новыми координатами
+	UpdatePosition(ctx context.Context, id string, position models.Position) (*models.Courier, error)            // переносит курьера id в абсолютную точку с устройства
+	AddScore(ctx context.Context, id string, delta int) (*models.Courier, error)                                 // начисляет курьеру очки за доставленные заказы
+	Track(ctx context.Context, id string, from, to time.Time) ([]models.TrackPoint, error)                       // возвращает историю перемещений курьера за период
+	NearbyCouriers(ctx context.Context, point models.Point, radius float64, limit int) ([]models.Courier, error) // возвращает ближайших к точке курьеров, radius в метрах, 0 - без ограничения
+	Transition(ctx context.Context, id string, to models.CourierState, orderID int64) (*models.Courier, error)   // переводит курьера в состояние to, orderID - заказ, с которым связан переход
+	Transitions(ctx context.Context, id string) ([]models.StateTransition, error)                                // возвращает историю смены состояний курьера
+	PickUp(ctx context.Context, id string, orderID int64, steps ...cache.TxStep) (*models.Courier, error)        // добавляет заказ к заказам курьера в пределах вместимости транспорта в одной транзакции с шагами steps
+	DropOff(ctx context.Context, id string, orderID int64) (*models.Courier, error)                              // убирает доставленный заказ, без заказов курьер снова свободен
+	Deliver(ctx context.Context, id string, orderID int64, steps ...cache.TxStep) (*models.Courier, error)       // убирает доставленный заказ и начисляет курьеру очко в одной транзакции с шагами steps
+	Release(ctx context.Context, id string) (*models.Courier, error)                                             // переводит курьера без заказов offline через допустимые переходы, курьер с заказами не меняется
+	Heartbeat(ctx context.Context, id string) error                                                              // продлевает присутствие курьера на presenceTTL
+	ExpirePresence(ctx context.Context) (int, error)                                                             // переводит offline курьеров с истекшим присутствием и возвращает их количество
 }
 
 type CourierService struct {
 	courierStorage storage.CourierStorager
-	allowedZone    geo.PolygonChecker
-	disabledZones  []geo.PolygonChecker
+	trackStorage   storage.TrackStorager
+	presence       storage.PresenceStorager
+	zones          geo.ZoneProvider
+	geofenceSink   events.GeofenceSink
+	suspiciousSink events.SuspiciousSink
+	clock          clock.Clock
+	rand           random.Rand
+	maxSpeed       float64 // м/с
+	presenceTTL    time.Duration
 }
 
-func NewCourierService(courierStorage storage.CourierStorager, allowedZone geo.PolygonChecker, disbledZones []geo.PolygonChecker) Courierer {
-	return &CourierService{courierStorage: courierStorage, allowedZone: allowedZone, disabledZones: disbledZones}
+// NewCourierService zones - источник актуальных зон, набор зон может меняться во время работы.
+// maxSpeed - максимальная скорость курьера в м/с, перемещения быстрее ограничиваются,
+// а SustainedViolations таких перемещений подряд записываются в suspiciousSink.
+// presenceTTL - время после последнего heartbeat, через которое курьер уходит offline.
+// rand - источник случайных точек, в которые попадает курьер без разрешенной точки рядом
+func NewCourierService(courierStorage storage.CourierStorager, trackStorage storage.TrackStorager, presence storage.PresenceStorager, zones geo.ZoneProvider, geofenceSink events.GeofenceSink, suspiciousSink events.SuspiciousSink, clock clock.Clock, rand random.Rand, maxSpeed float64, presenceTTL time.Duration) Courierer {
+	return &CourierService{
+		courierStorage: courierStorage,
+		trackStorage:   trackStorage,
+		presence:       presence,
+		zones:          zones,
+		geofenceSink:   geofenceSink,
+		suspiciousSink: suspiciousSink,
+		clock:          clock,
+		rand:           rand,
+		maxSpeed:       maxSpeed,
+		presenceTTL:    presenceTTL,
+	}
 }
 
-func (c *CourierService) checkCoureier() {
+// allowedDistance возвращает расстояние в метрах, которое курьер мог преодолеть с последнего перемещения к моменту now,
+// с учетом накопленного запаса. Для курьера без времени последнего перемещения ограничения нет
+func (c *CourierService) allowedDistance(courier models.Courier, now time.Time) (float64, bool) {
+	if courier.UpdatedAt.IsZero() {
+		return 0, false
+	}
+
+	elapsed := now.Sub(courier.UpdatedAt).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
 
+	return c.maxSpeed*elapsed + courier.SpeedAllowance, true
 }
-func (c *CourierService) GetCourier(ctx context.Context) (*models.Courier, error) {
-	var courier *models.Courier
-	var err error
 
-	// получаем курьера из хранилища используя метод GetOne из storage/courier.go
-	courier, err = c.courierStorage.GetOne(ctx)
+// spendAllowance сохраняет у курьера неизрасходованную часть допустимого расстояния limit после перемещения на distance,
+// но не больше SpeedBurstDistance
+func spendAllowance(courier *models.Courier, limit, distance float64) {
+	courier.SpeedAllowance = math.Min(SpeedBurstDistance, math.Max(0, limit-distance))
+}
 
+// reportSuspicious записывает перемещение курьера в точку to с недопустимой скоростью, если нарушения продолжаются
+// SustainedViolations раз подряд. courier - состояние курьера до перемещения, at - время перемещения,
+// violations - число нарушений подряд вместе с этим
+func (c *CourierService) reportSuspicious(ctx context.Context, courier models.Courier, to models.Point, distance float64, at time.Time, violations int, action models.SuspiciousAction) {
+	if violations < SustainedViolations {
+		return
+	}
+
+	event := models.SuspiciousEvent{
+		Type:       models.SuspiciousSpeed,
+		Action:     action,
+		CourierID:  courier.ID,
+		From:       courier.Location,
+		To:         to,
+		Distance:   distance,
+		Elapsed:    at.Sub(courier.UpdatedAt).Seconds(),
+		Violations: violations,
+		Timestamp:  c.clock.Now(),
+	}
+
+	// при нулевом интервале скорость бесконечна и не сериализуется в json, тогда она не заполняется
+	if event.Elapsed > 0 {
+		event.Speed = distance / event.Elapsed
+	}
+
+	err := c.suspiciousSink.Publish(ctx, event)
 	if err != nil {
-		return nil, err
+		log.Printf("error while publishing suspicious event: %v", err)
+	}
+}
+
+// recordTrack добавляет текущее положение курьера в его трек, position - данные устройства, может быть nil.
+// Ошибка записи трека не должна мешать перемещению, поэтому она только логируется
+func (c *CourierService) recordTrack(ctx context.Context, courier models.Courier, position *models.Position) {
+	point := models.TrackPoint{
+		Location:  courier.Location,
+		Timestamp: c.clock.Now(),
 	}
 
-	if courier == nil {
-		courier = &models.Courier{
-			Location: models.Point{
-				Lat: DefaultCourierLat,
-				Lng: DefaultCourierLng,
-			},
+	if position != nil {
+		point.Accuracy = position.Accuracy
+		point.Heading = position.Heading
+		point.Speed = position.Speed
+		if !position.Timestamp.IsZero() {
+			point.DeviceTime = &position.Timestamp
+		}
+	}
+
+	err := c.trackStorage.Append(ctx, courier.ID, point)
+	if err != nil {
+		log.Printf("error while recording courier %s track: %v", courier.ID, err)
+	}
+}
+
+// zoneIDsAt возвращает идентификаторы всех загруженных зон, в которых находится точка
+func (c *CourierService) zoneIDsAt(zones *geo.ZoneSet, location models.Point) map[string]struct{} {
+	found := zones.ZoneIDsAt(geo.Point{
+		Lat: location.Lat,
+		Lng: location.Lng,
+	})
+
+	ids := make(map[string]struct{}, len(found))
+	for i := range found {
+		ids[found[i]] = struct{}{}
+	}
+
+	return ids
+}
+
+// publishGeofenceEvents сравнивает зоны в точке from и в текущей точке курьера
+// и отправляет события входа и выхода из зон.
+// target - точка, в которую курьер пытался попасть: курьер останавливается на границе запрещенной зоны
+// и никогда в нее не входит, поэтому о попытке входа сообщает отдельное событие с координатами target
+func (c *CourierService) publishGeofenceEvents(ctx context.Context, zones *geo.ZoneSet, courier models.Courier, from, target models.Point) {
+	if from == courier.Location && target == courier.Location {
+		return
+	}
+
+	before := c.zoneIDsAt(zones, from)
+	after := c.zoneIDsAt(zones, courier.Location)
+	now := c.clock.Now()
+
+	publish := func(eventType models.GeofenceEventType, zoneID string, location models.Point) {
+		err := c.geofenceSink.Publish(ctx, models.GeofenceEvent{
+			Type:      eventType,
+			ZoneID:    zoneID,
+			Courier:   courier,
+			Location:  location,
+			Timestamp: now,
+		})
+		if err != nil {
+			log.Printf("error while publishing geofence event: %v", err)
+		}
+	}
+
+	for id := range before {
+		if _, ok := after[id]; !ok {
+			publish(models.GeofenceExit, id, courier.Location)
 		}
 	}
 
+	for id := range after {
+		if _, ok := before[id]; !ok {
+			publish(models.GeofenceEnter, id, courier.Location)
+		}
+	}
+
+	if target == courier.Location {
+		return
+	}
+
+	attempted := geo.ZonesAt(geo.Point{
+		Lat: target.Lat,
+		Lng: target.Lng,
+	}, zones.All())
+
+	for i := range attempted {
+		if attempted[i].Allowed() {
+			continue
+		}
+
+		if _, ok := after[attempted[i].ID()]; !ok {
+			publish(models.GeofenceViolation, attempted[i].ID(), target)
+		}
+	}
+}
+
+// snapToAllowed возвращает ближайшую к location разрешенную точку, курьер останавливается на границе зоны.
+// Если ближайшую точку найти не удалось, курьер перемещается в случайную точку разрешенной зоны
+func (c *CourierService) snapToAllowed(zones *geo.ZoneSet, location models.Point) (models.Point, error) {
+	point, err := geo.NearestAllowedPoint(geo.Point{
+		Lat: location.Lat,
+		Lng: location.Lng,
+	}, zones.Allowed, zones.Disabled)
+
+	if err != nil {
+		point, err = geo.GetRandomAllowedLocation(zones.Allowed, zones.Disabled, c.rand)
+		if err != nil {
+			return location, err
+		}
+	}
+
+	return models.Point{
+		Lat: point.Lat,
+		Lng: point.Lng,
+	}, nil
+}
+
+func (c *CourierService) GetCourier(ctx context.Context, id string) (*models.Courier, error) {
+	var from models.Point
+
 	// проверяем, что курьер находится в разрешенной зоне
-	// если нет, то перемещаем его в случайную точку в разрешенной зоне
-	// сохраняем новые координаты курьера
-	if !geo.CheckPointIsAllowed(geo.Point{
-		courier.Location.Lat,
-		courier.Location.Lng,
-	}, c.allowedZone, c.disabledZones) {
+	// если нет, то перемещаем его в ближайшую точку разрешенной зоны
+	// и сохраняем новые координаты курьера, курьер в разрешенной зоне не сохраняется
+	zones := c.zones.Zones()
+	courier, err := c.update(ctx, id, func(courier *models.Courier) ([]models.StateTransition, error) {
+		from = courier.Location
 
-		fmt.Println("not allowed", courier.Location.Lat, courier.Location.Lng)
-		rp := geo.GetRandomAllowedLocation(c.allowedZone, c.disabledZones)
+		if geo.CheckPointIsAllowed(geo.Point{
+			Lat: courier.Location.Lat,
+			Lng: courier.Location.Lng,
+		}, zones.Allowed, zones.Disabled) {
+			return nil, storage.ErrNotModified
+		}
 
-		courier.Location = models.Point{
-			Lat: rp.Lat,
-			Lng: rp.Lng,
+		location, err := c.snapToAllowed(zones, courier.Location)
+		if err != nil {
+			return nil, err
 		}
 
+		courier.Location = location
+
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	c.courierStorage.Save(ctx, *courier)
+	if courier.Location != from {
+		c.publishGeofenceEvents(ctx, zones, *courier, from, courier.Location)
+		c.recordTrack(ctx, *courier, nil)
+	}
 
 	return courier, nil
 }
 
-// MoveCourier : direction - направление движения курьера, zoom - зум карты
-func (c *CourierService) MoveCourier(courier models.Courier, direction, zoom int) error {
+func (c *CourierService) Exists(ctx context.Context, id string) (bool, error) {
+	return c.courierStorage.Exists(ctx, id)
+}
+
+func (c *CourierService) CreateCourier(ctx context.Context, vehicle models.Vehicle) (*models.Courier, error) {
+	if vehicle == "" {
+		vehicle = models.DefaultVehicle
+	}
 
-	var err error
+	if !vehicle.Valid() {
+		return nil, fmt.Errorf("%w %q", ErrUnknownVehicle, vehicle)
+	}
+
+	id, err := c.courierStorage.GenerateUniqueID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	courier := models.Courier{
+		ID: strconv.FormatInt(id, 10),
+		Location: models.Point{
+			Lat: DefaultCourierLat,
+			Lng: DefaultCourierLng,
+		},
+		UpdatedAt:      c.clock.Now(),
+		SpeedAllowance: SpeedBurstDistance,
+		State:          models.CourierOffline,
+		StateChangedAt: c.clock.Now(),
+		Vehicle:        vehicle,
+	}
+
+	// новый курьер выходит на линию только с началом смены
+	err = c.courierStorage.Save(ctx, courier)
+	if err != nil {
+		return nil, err
+	}
+
+	c.recordTrack(ctx, courier, nil)
+
+	return &courier, nil
+}
+
+func (c *CourierService) ListCouriers(ctx context.Context) ([]models.Courier, error) {
+	return c.courierStorage.List(ctx)
+}
+
+func (c *CourierService) DeleteCourier(ctx context.Context, id string) error {
+	deleted, err := c.courierStorage.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return ErrCourierNotFound
+	}
+
+	return nil
+}
+
+// MoveCourier : direction - направление движения курьера, zoom - зум карты.
+// Шаг считается от положения курьера в хранилище, а не от снимка вызывающего, поэтому параллельные шаги не теряются
+func (c *CourierService) MoveCourier(ctx context.Context, id string, direction, zoom int) (*models.Courier, error) {
+	now := c.clock.Now()
+	zones := c.zones.Zones()
 
 	// точность перемещения зависит от зума карты использовать формулу 0.001 / 2^(zoom - 14)
 	// 14 - это максимальный зум карты
@@ -97,42 +404,488 @@ func (c *CourierService) MoveCourier(courier models.Courier, direction, zoom int
 
 	d := 0.001 / math.Pow(2, float64(zoom-14))
 
-	switch direction {
-	case DirectionUp:
-		courier.Location.Lat += d
-	case DirectionDown:
-		courier.Location.Lat -= d
-	case DirectionLeft:
-		courier.Location.Lng -= d
-	case DirectionRight:
-		courier.Location.Lng += d
+	// положение до перемещения и укороченный шаг запоминаются для событий после сохранения
+	var origin models.Courier
+	var requested, target models.Point
+	var distance float64
+	var clamped bool
+
+	courier, err := c.update(ctx, id, func(courier *models.Courier) ([]models.StateTransition, error) {
+		var err error
+
+		origin = *courier
+		clamped = false
+
+		from := geo.Point{
+			Lat: courier.Location.Lat,
+			Lng: courier.Location.Lng,
+		}
+
+		switch direction {
+		case DirectionUp:
+			courier.Location.Lat += d
+		case DirectionDown:
+			courier.Location.Lat -= d
+		case DirectionLeft:
+			courier.Location.Lng -= d
+		case DirectionRight:
+			courier.Location.Lng += d
+		default:
+			return nil, errors.New("incorrect direction")
+		}
+
+		// шаг длиннее, чем курьер мог проехать с последнего перемещения, укорачивается до допустимого,
+		// поэтому частые сообщения с минимальным зумом не позволяют пересечь город мгновенно
+		if limit, ok := c.allowedDistance(origin, now); ok {
+			to := geo.Point{
+				Lat: courier.Location.Lat,
+				Lng: courier.Location.Lng,
+			}
+
+			distance = geo.Distance(from, to)
+			if distance > limit {
+				clamped = true
+				requested = courier.Location
+				courier.SpeedViolations++
+
+				stop := geo.Interpolate(from, to, limit/distance)
+				courier.Location = models.Point{
+					Lat: stop.Lat,
+					Lng: stop.Lng,
+				}
+			} else {
+				courier.SpeedViolations = 0
+			}
+
+			spendAllowance(courier, limit, math.Min(distance, limit))
+		}
+
+		// далее нужно проверить, что курьер не вышел за границы зоны
+		// если вышел, то курьер останавливается на границе зоны
+		// при большом шаге путь может пересечь запрещенную зону целиком, поэтому проверяется весь отрезок перемещения
+		target = courier.Location
+		if geo.CheckPointIsAllowed(from, zones.Allowed, zones.Disabled) {
+			stop, _ := geo.ClampPath(from, geo.Point{
+				Lat: courier.Location.Lat,
+				Lng: courier.Location.Lng,
+			}, zones.Allowed, zones.Disabled)
+
+			courier.Location = models.Point{
+				Lat: stop.Lat,
+				Lng: stop.Lng,
+			}
+		} else {
+			courier.Location, err = c.snapToAllowed(zones, courier.Location)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		courier.UpdatedAt = now
+
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if clamped {
+		c.reportSuspicious(ctx, origin, requested, distance, now, courier.SpeedViolations, models.SuspiciousClamped)
+	}
+
+	c.publishGeofenceEvents(ctx, zones, *courier, origin.Location, target)
+
+	if courier.Location != origin.Location {
+		c.recordTrack(ctx, *courier, nil)
+	}
+
+	return courier, nil
+}
+
+// UpdatePosition в отличие от MoveCourier принимает абсолютные координаты с телефона курьера.
+// Реальный курьер мог объехать запрещенную зону между замерами, поэтому проверяется только новая точка:
+// если она вне разрешенной области, курьер переносится в ближайшую разрешенную точку.
+// Скорость считается по времени устройства: оно точнее отражает интервал между замерами, чем время доставки сообщений,
+// но не может быть позже времени сервера и раньше предыдущего обновления
+func (c *CourierService) UpdatePosition(ctx context.Context, id string, position models.Position) (*models.Courier, error) {
+	err := validatePosition(position)
+	if err != nil {
+		return nil, err
+	}
+
+	measured := c.clock.Now()
+	if !position.Timestamp.IsZero() && position.Timestamp.Before(measured) {
+		measured = position.Timestamp
+	}
+
+	zones := c.zones.Zones()
+
+	var origin models.Courier
+	var at time.Time
+	var distance, limit float64
+	var rejected bool
+
+	courier, err := c.update(ctx, id, func(courier *models.Courier) ([]models.StateTransition, error) {
+		var err error
+		var ok bool
+
+		origin = *courier
+		rejected = false
+
+		at = measured
+		if at.Before(courier.UpdatedAt) {
+			at = courier.UpdatedAt
+		}
+
+		// положение дальше, чем курьер мог проехать с последнего обновления, отклоняется целиком:
+		// в отличие от шага с клавиатуры неизвестно, где курьер находится на самом деле.
+		// Сохраняется только счетчик нарушений подряд
+		if limit, ok = c.allowedDistance(*courier, at); ok {
+			distance = geo.Distance(geo.Point{
+				Lat: courier.Location.Lat,
+				Lng: courier.Location.Lng,
+			}, geo.Point{
+				Lat: position.Location.Lat,
+				Lng: position.Location.Lng,
+			})
+
+			if distance > limit {
+				rejected = true
+				courier.SpeedViolations++
+				return nil, nil
+			}
+
+			courier.SpeedViolations = 0
+			spendAllowance(courier, limit, distance)
+		}
+
+		courier.Location = position.Location
+		courier.UpdatedAt = at
+
+		if !geo.CheckPointIsAllowed(geo.Point{
+			Lat: courier.Location.Lat,
+			Lng: courier.Location.Lng,
+		}, zones.Allowed, zones.Disabled) {
+			courier.Location, err = c.snapToAllowed(zones, courier.Location)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return nil, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rejected {
+		c.reportSuspicious(ctx, origin, position.Location, distance, at, courier.SpeedViolations, models.SuspiciousRejected)
+		return nil, fmt.Errorf("%w: %.0f m, allowed %.0f m", ErrSpeedExceeded, distance, limit)
+	}
+
+	c.publishGeofenceEvents(ctx, zones, *courier, origin.Location, position.Location)
+	c.recordTrack(ctx, *courier, &position)
+
+	return courier, nil
+}
+
+// validatePosition проверяет диапазоны значений, пришедших с устройства
+func validatePosition(position models.Position) error {
+	lat, lng := position.Location.Lat, position.Location.Lng
+
+	switch {
+	case math.IsNaN(lat) || lat < -90 || lat > 90:
+		return fmt.Errorf("%w: latitude %v out of range", ErrInvalidPosition, lat)
+	case math.IsNaN(lng) || lng < -180 || lng > 180:
+		return fmt.Errorf("%w: longitude %v out of range", ErrInvalidPosition, lng)
+	case math.IsNaN(position.Accuracy) || position.Accuracy < 0:
+		return fmt.Errorf("%w: negative accuracy", ErrInvalidPosition)
+	case math.IsNaN(position.Speed) || position.Speed < 0:
+		return fmt.Errorf("%w: negative speed", ErrInvalidPosition)
+	case math.IsNaN(position.Heading) || position.Heading < 0 || position.Heading >= 360:
+		return fmt.Errorf("%w: heading %v out of range [0, 360)", ErrInvalidPosition, position.Heading)
+	}
+
+	return nil
+}
+
+func (c *CourierService) Transition(ctx context.Context, id string, to models.CourierState, orderID int64) (*models.Courier, error) {
+	if !to.Valid() {
+		return nil, fmt.Errorf("%w %q", ErrUnknownState, to)
+	}
+
+	return c.update(ctx, id, func(courier *models.Courier) ([]models.StateTransition, error) {
+		// курьер с заказами не может освободиться, пока не доставит их
+		if (to == models.CourierIdle || to == models.CourierOffline) && len(courier.Orders) > 0 {
+			return nil, fmt.Errorf("%w: courier carries %d orders", &InvalidTransitionError{From: courier.State, To: to}, len(courier.Orders))
+		}
+
+		transition, err := c.transition(courier, to, orderID)
+		if err != nil {
+			return nil, err
+		}
+
+		return []models.StateTransition{transition}, nil
+	})
+}
+
+// transition переводит курьера в состояние to и возвращает запись о переходе, которая сохраняется вместе с курьером
+func (c *CourierService) transition(courier *models.Courier, to models.CourierState, orderID int64) (models.StateTransition, error) {
+	if !courier.State.CanTransitionTo(to) {
+		return models.StateTransition{}, &InvalidTransitionError{From: courier.State, To: to}
+	}
+
+	transition := models.StateTransition{
+		CourierID: courier.ID,
+		From:      courier.State,
+		To:        to,
+		OrderID:   orderID,
+		Timestamp: c.clock.Now(),
+	}
+
+	courier.State = to
+	courier.StateChangedAt = transition.Timestamp
+
+	return transition, nil
+}
+
+// PickUp свободный курьер проходит назначение, получение и начинает доставку,
+// курьер с заказами забирает еще один и продолжает доставку.
+// Вместимость проверяется в той же транзакции, в которой сохраняется курьер и выполняются шаги steps,
+// например закрепление заказа: если курьер не может забрать заказ, заказ остается доступным
+func (c *CourierService) PickUp(ctx context.Context, id string, orderID int64, steps ...cache.TxStep) (*models.Courier, error) {
+	return c.update(ctx, id, func(courier *models.Courier) ([]models.StateTransition, error) {
+		if courier.Carries(orderID) {
+			return nil, storage.ErrNotModified
+		}
+
+		if courier.FreeCapacity() <= 0 {
+			return nil, fmt.Errorf("%w: %s carries up to %d orders", ErrCapacityExceeded, courier.Vehicle, courier.Vehicle.Capacity())
+		}
+
+		var states []models.CourierState
+		switch courier.State {
+		case models.CourierIdle:
+			states = []models.CourierState{models.CourierAssigned, models.CourierPickingUp, models.CourierDelivering}
+		case models.CourierDelivering:
+			states = []models.CourierState{models.CourierPickingUp, models.CourierDelivering}
+		default:
+			return nil, &InvalidTransitionError{From: courier.State, To: models.CourierPickingUp}
+		}
+
+		courier.Orders = append(courier.Orders, orderID)
+
+		transitions := make([]models.StateTransition, 0, len(states))
+		for _, state := range states {
+			transition, err := c.transition(courier, state, orderID)
+			if err != nil {
+				return nil, err
+			}
+
+			transitions = append(transitions, transition)
+		}
+
+		return transitions, nil
+	}, steps...)
+}
+
+func (c *CourierService) DropOff(ctx context.Context, id string, orderID int64) (*models.Courier, error) {
+	return c.update(ctx, id, func(courier *models.Courier) ([]models.StateTransition, error) {
+		return c.dropOff(courier, orderID)
+	})
+}
+
+// Deliver очко за заказ, итоги и оплата из шагов steps сохраняются вместе с курьером,
+// поэтому доставка не может засчитаться частично
+func (c *CourierService) Deliver(ctx context.Context, id string, orderID int64, steps ...cache.TxStep) (*models.Courier, error) {
+	return c.update(ctx, id, func(courier *models.Courier) ([]models.StateTransition, error) {
+		transitions, err := c.dropOff(courier, orderID)
+		if err != nil {
+			return nil, err
+		}
+
+		courier.Score++
+
+		return transitions, nil
+	}, steps...)
+}
+
+// dropOff убирает заказ из заказов курьера и возвращает переход в idle, если заказов не осталось
+func (c *CourierService) dropOff(courier *models.Courier, orderID int64) ([]models.StateTransition, error) {
+	if !courier.Carries(orderID) {
+		return nil, fmt.Errorf("%w: order %d, courier %s", ErrOrderNotCarried, orderID, courier.ID)
+	}
+
+	orders := make([]int64, 0, len(courier.Orders)-1)
+	for i := range courier.Orders {
+		if courier.Orders[i] != orderID {
+			orders = append(orders, courier.Orders[i])
+		}
+	}
+	courier.Orders = orders
+
+	if len(courier.Orders) > 0 {
+		return nil, nil
+	}
+
+	transition, err := c.transition(courier, models.CourierIdle, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return []models.StateTransition{transition}, nil
+}
+
+// Release снимает с линии курьера без заказов: от назначенного заказа курьер отказывается,
+// курьер в пути без заказов завершает доставку, затем курьер уходит offline.
+// Курьер с заказами не меняется и уходит с линии после доставки последнего заказа
+func (c *CourierService) Release(ctx context.Context, id string) (*models.Courier, error) {
+	return c.update(ctx, id, func(courier *models.Courier) ([]models.StateTransition, error) {
+		if len(courier.Orders) > 0 {
+			return nil, storage.ErrNotModified
+		}
+
+		return c.release(courier)
+	})
+}
+
+// release переводит курьера без заказов в offline через допустимые переходы и возвращает записи о переходах,
+// storage.ErrNotModified если курьер уже offline
+func (c *CourierService) release(courier *models.Courier) ([]models.StateTransition, error) {
+	var states []models.CourierState
+	switch courier.State {
+	case models.CourierOffline:
+		return nil, storage.ErrNotModified
+	case models.CourierIdle:
+		states = []models.CourierState{models.CourierOffline}
+	case models.CourierAssigned:
+		states = []models.CourierState{models.CourierIdle, models.CourierOffline}
+	case models.CourierPickingUp:
+		states = []models.CourierState{models.CourierDelivering, models.CourierIdle, models.CourierOffline}
+	case models.CourierDelivering:
+		states = []models.CourierState{models.CourierIdle, models.CourierOffline}
 	default:
-		return errors.New("incorrect direction")
+		return nil, &InvalidTransitionError{From: courier.State, To: models.CourierOffline}
+	}
+
+	transitions := make([]models.StateTransition, 0, len(states))
+	for _, state := range states {
+		transition, err := c.transition(courier, state, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		transitions = append(transitions, transition)
+	}
+
+	return transitions, nil
+}
+
+func (c *CourierService) Heartbeat(ctx context.Context, id string) error {
+	return c.presence.Touch(ctx, id, c.clock.Now(), c.presenceTTL)
+}
+
+// ExpirePresence курьер без заказов и без heartbeat уходит offline из любого состояния и пропадает из гео индекса.
+// Курьер с заказами по правилам переходов не может уйти offline, поэтому он только убирается из гео индекса
+// и вернется в него при следующем сохранении. Его heartbeat не забывается, чтобы проверить курьера снова,
+// когда он доставит заказы
+func (c *CourierService) ExpirePresence(ctx context.Context) (int, error) {
+	ids, err := c.presence.Expired(ctx, c.clock.Now().Add(-c.presenceTTL))
+	if err != nil {
+		return 0, err
 	}
 
-	// далее нужно проверить, что курьер не вышел за границы зоны
-	// если вышел, то нужно переместить его в случайную точку внутри зоны
-	if !geo.CheckPointIsAllowed(geo.Point{
-		courier.Location.Lat,
-		courier.Location.Lng,
-	}, c.allowedZone, c.disabledZones) {
+	expired := 0
+	for _, id := range ids {
+		// состояние проверяется в той же операции, что и переход, поэтому курьер, взявший заказ
+		// после чтения списка, не уйдет offline
+		var offline, carrying bool
+
+		_, err := c.update(ctx, id, func(courier *models.Courier) ([]models.StateTransition, error) {
+			offline, carrying = false, false
 
-		rp := geo.GetRandomAllowedLocation(c.allowedZone, c.disabledZones)
+			if len(courier.Orders) > 0 {
+				carrying = true
+				return nil, storage.ErrNotModified
+			}
 
-		courier.Location = models.Point{
-			Lat: rp.Lat,
-			Lng: rp.Lng,
+			transitions, err := c.release(courier)
+			offline = err == nil
+
+			return transitions, err
+		})
+		if err != nil && !errors.Is(err, ErrCourierNotFound) {
+			return expired, err
+		}
+
+		if carrying {
+			err = c.courierStorage.Unindex(ctx, id)
+			if err != nil {
+				return expired, err
+			}
+
+			expired++
+			continue
+		}
+
+		if offline {
+			expired++
+		}
+
+		err = c.presence.Forget(ctx, id)
+		if err != nil {
+			return expired, err
 		}
 	}
 
-	fmt.Println("move", courier, d)
+	return expired, nil
+}
 
-	// далее сохранить изменения в хранилище
-	ctx := context.Background()
-	err = c.courierStorage.Save(ctx, courier)
+// update атомарно изменяет курьера через хранилище, ErrCourierNotFound если курьера нет
+func (c *CourierService) update(ctx context.Context, id string, fn storage.UpdateFunc, steps ...cache.TxStep) (*models.Courier, error) {
+	courier, err := c.courierStorage.Update(ctx, id, fn, steps...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	if courier == nil {
+		return nil, ErrCourierNotFound
+	}
+
+	return courier, nil
+}
+
+func (c *CourierService) Transitions(ctx context.Context, id string) ([]models.StateTransition, error) {
+	return c.courierStorage.Transitions(ctx, id)
+}
+
+func (c *CourierService) NearbyCouriers(ctx context.Context, point models.Point, radius float64, limit int) ([]models.Courier, error) {
+	if radius <= 0 {
+		return c.courierStorage.Nearest(ctx, point.Lng, point.Lat, limit)
+	}
+
+	couriers, err := c.courierStorage.GetByRadius(ctx, point.Lng, point.Lat, radius, "m")
+	if err != nil {
+		return nil, err
+	}
+
+	// курьеры отсортированы по расстоянию, поэтому ограничение оставляет ближайших
+	if limit > 0 && len(couriers) > limit {
+		couriers = couriers[:limit]
+	}
+
+	return couriers, nil
+}
+
+func (c *CourierService) Track(ctx context.Context, id string, from, to time.Time) ([]models.TrackPoint, error) {
+	return c.trackStorage.Range(ctx, id, from, to)
+}
+
+func (c *CourierService) AddScore(ctx context.Context, id string, delta int) (*models.Courier, error) {
+	return c.update(ctx, id, func(courier *models.Courier) ([]models.StateTransition, error) {
+		courier.Score += delta
+
+		return nil, nil
+	})
 }
@@ -0,0 +1,648 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"github.com/GoGerman/geo-task/clock"
+	"github.com/GoGerman/geo-task/geo"
+	"github.com/GoGerman/geo-task/internal/testutil"
+	"github.com/GoGerman/geo-task/module/courier/events"
+	"github.com/GoGerman/geo-task/module/courier/models"
+	"github.com/GoGerman/geo-task/module/courier/service"
+	"sync"
+	"testing"
+	"time"
+)
+
+// suspiciousRecorder запоминает подозрительные события
+type suspiciousRecorder struct {
+	mu     sync.Mutex
+	events []models.SuspiciousEvent
+}
+
+func (r *suspiciousRecorder) Publish(ctx context.Context, event models.SuspiciousEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *suspiciousRecorder) Events() []models.SuspiciousEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]models.SuspiciousEvent(nil), r.events...)
+}
+
+// newTestCourierService создает сервис курьеров в новом окружении с зонами specs, без specs - в городе
+func newTestCourierService(t *testing.T, opts testutil.CourierOptions, specs ...geo.ZoneSpec) (service.Courierer, *clock.Manual) {
+	t.Helper()
+
+	env := testutil.NewEnv(t, specs...)
+
+	return env.Couriers(opts), env.Clock
+}
+
+func TestConcurrentUpdatesAreNotLost(t *testing.T) {
+	ctx := context.Background()
+	couriers, _ := newTestCourierService(t, testutil.CourierOptions{})
+	courier := testutil.IdleCourier(t, couriers, models.VehicleBicycle)
+
+	const scores = 20
+	orders := []int64{1, 2, 3}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, scores+len(orders))
+	for i := 0; i < scores; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := couriers.AddScore(ctx, courier.ID, 1)
+			errs <- err
+		}()
+	}
+	for _, orderID := range orders {
+		wg.Add(1)
+		go func(orderID int64) {
+			defer wg.Done()
+			_, err := couriers.PickUp(ctx, courier.ID, orderID)
+			errs <- err
+		}(orderID)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent update error = %v", err)
+		}
+	}
+
+	got, err := couriers.GetCourier(ctx, courier.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Score != scores {
+		t.Errorf("Score = %d, want %d", got.Score, scores)
+	}
+	if len(got.Orders) != len(orders) {
+		t.Errorf("Orders = %v, want %v", got.Orders, orders)
+	}
+	if got.State != models.CourierDelivering {
+		t.Errorf("State = %s, want %s", got.State, models.CourierDelivering)
+	}
+
+	// выход на линию, три перехода первого заказа и по два перехода для остальных
+	transitions, err := couriers.Transitions(ctx, courier.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 1 + 3 + 2*(len(orders)-1); len(transitions) != want {
+		t.Errorf("Transitions() = %d, want %d", len(transitions), want)
+	}
+}
+
+func TestConcurrentPickUpsRespectCapacity(t *testing.T) {
+	ctx := context.Background()
+	couriers, _ := newTestCourierService(t, testutil.CourierOptions{})
+	courier := testutil.IdleCourier(t, couriers, models.VehicleFoot)
+
+	const attempts = 10
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	picked := 0
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(orderID int64) {
+			defer wg.Done()
+
+			_, err := couriers.PickUp(ctx, courier.ID, orderID)
+			if err != nil && !errors.Is(err, service.ErrCapacityExceeded) {
+				t.Errorf("PickUp() error = %v", err)
+				return
+			}
+
+			if err == nil {
+				mu.Lock()
+				picked++
+				mu.Unlock()
+			}
+		}(int64(i + 1))
+	}
+	wg.Wait()
+
+	got, err := couriers.GetCourier(ctx, courier.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	capacity := models.VehicleFoot.Capacity()
+	if picked != capacity || len(got.Orders) != capacity {
+		t.Errorf("picked %d orders, courier carries %v, want capacity %d", picked, got.Orders, capacity)
+	}
+}
+
+func TestMoveCourierStartsFromStoredLocation(t *testing.T) {
+	ctx := context.Background()
+	couriers, clk := newTestCourierService(t, testutil.CourierOptions{})
+	courier := testutil.IdleCourier(t, couriers, models.VehicleFoot)
+
+	// шаги выполняются по очереди, как сообщения одного WebSocket, но каждый читает курьера из хранилища
+	for i := 0; i < 3; i++ {
+		clk.Add(time.Minute)
+
+		_, err := couriers.MoveCourier(ctx, courier.ID, service.DirectionRight, 14)
+		if err != nil {
+			t.Fatalf("MoveCourier() error = %v", err)
+		}
+	}
+
+	got, err := couriers.GetCourier(ctx, courier.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := courier.Location.Lng + 0.003; got.Location.Lng < want-1e-9 || got.Location.Lng > want+1e-9 {
+		t.Errorf("Lng = %v, want %v", got.Location.Lng, want)
+	}
+}
+
+func TestUpdateMissingCourier(t *testing.T) {
+	couriers, _ := newTestCourierService(t, testutil.CourierOptions{})
+
+	_, err := couriers.AddScore(context.Background(), "404", 1)
+	if !errors.Is(err, service.ErrCourierNotFound) {
+		t.Errorf("AddScore() error = %v, want %v", err, service.ErrCourierNotFound)
+	}
+}
+
+func TestMoveCourierToleratesBurst(t *testing.T) {
+	ctx := context.Background()
+	recorder := &suspiciousRecorder{}
+	couriers, _ := newTestCourierService(t, testutil.CourierOptions{Suspicious: recorder})
+	courier := testutil.IdleCourier(t, couriers, models.VehicleFoot)
+
+	// шаг около 56 м без паузы укладывается в запас и не укорачивается
+	first, err := couriers.MoveCourier(ctx, courier.ID, service.DirectionRight, 14)
+	if err != nil {
+		t.Fatalf("MoveCourier() error = %v", err)
+	}
+
+	if want := courier.Location.Lng + 0.001; first.Location.Lng < want-1e-9 || first.Location.Lng > want+1e-9 {
+		t.Errorf("Lng = %v, want %v", first.Location.Lng, want)
+	}
+
+	if first.SpeedViolations != 0 {
+		t.Errorf("SpeedViolations = %d, want 0", first.SpeedViolations)
+	}
+
+	// следующий такой же шаг превышает остаток запаса и укорачивается, но единичное нарушение не записывается
+	second, err := couriers.MoveCourier(ctx, courier.ID, service.DirectionRight, 14)
+	if err != nil {
+		t.Fatalf("MoveCourier() error = %v", err)
+	}
+
+	if second.Location.Lng >= first.Location.Lng+0.001-1e-9 || second.Location.Lng <= first.Location.Lng {
+		t.Errorf("second step Lng = %v, want clamped between %v and %v", second.Location.Lng, first.Location.Lng, first.Location.Lng+0.001)
+	}
+
+	if second.SpeedViolations != 1 || second.SpeedAllowance != 0 {
+		t.Errorf("SpeedViolations = %d, SpeedAllowance = %v, want 1 and 0", second.SpeedViolations, second.SpeedAllowance)
+	}
+
+	if got := recorder.Events(); len(got) != 0 {
+		t.Errorf("suspicious events = %+v, want none", got)
+	}
+}
+
+func TestSustainedSpeedViolationsAreReported(t *testing.T) {
+	ctx := context.Background()
+	recorder := &suspiciousRecorder{}
+	couriers, clk := newTestCourierService(t, testutil.CourierOptions{Suspicious: recorder})
+	courier := testutil.IdleCourier(t, couriers, models.VehicleFoot)
+
+	// около 5 км за секунду
+	jump := models.Position{Location: models.Point{Lat: courier.Location.Lat + 0.045, Lng: courier.Location.Lng}}
+
+	for i := 1; i <= service.SustainedViolations; i++ {
+		clk.Add(time.Second)
+
+		_, err := couriers.UpdatePosition(ctx, courier.ID, jump)
+		if !errors.Is(err, service.ErrSpeedExceeded) {
+			t.Fatalf("UpdatePosition() error = %v, want %v", err, service.ErrSpeedExceeded)
+		}
+
+		want := 0
+		if i >= service.SustainedViolations {
+			want = 1
+		}
+		if got := len(recorder.Events()); got != want {
+			t.Fatalf("after %d violations got %d suspicious events, want %d", i, got, want)
+		}
+	}
+
+	event := recorder.Events()[0]
+	if event.Action != models.SuspiciousRejected || event.Violations != service.SustainedViolations || event.To != jump.Location {
+		t.Errorf("event = %+v", event)
+	}
+
+	// обычное перемещение сбрасывает счетчик, следующий скачок снова считается единичным
+	clk.Add(time.Second)
+	near := models.Position{Location: models.Point{Lat: courier.Location.Lat + 0.0001, Lng: courier.Location.Lng}}
+	got, err := couriers.UpdatePosition(ctx, courier.ID, near)
+	if err != nil {
+		t.Fatalf("UpdatePosition() error = %v", err)
+	}
+	if got.SpeedViolations != 0 {
+		t.Errorf("SpeedViolations = %d, want 0", got.SpeedViolations)
+	}
+
+	clk.Add(time.Second)
+	_, err = couriers.UpdatePosition(ctx, courier.ID, jump)
+	if !errors.Is(err, service.ErrSpeedExceeded) {
+		t.Fatalf("UpdatePosition() error = %v, want %v", err, service.ErrSpeedExceeded)
+	}
+
+	if got := len(recorder.Events()); got != 1 {
+		t.Errorf("got %d suspicious events, want 1", got)
+	}
+}
+
+func TestUpdatePositionUsesDeviceTime(t *testing.T) {
+	ctx := context.Background()
+	couriers, clk := newTestCourierService(t, testutil.CourierOptions{})
+	courier := testutil.IdleCourier(t, couriers, models.VehicleFoot)
+	start := clk.Now()
+
+	// около 1 км, за 100 секунд по времени сервера допустимо, за 10 секунд по времени устройства - нет
+	far := models.Point{Lat: courier.Location.Lat + 0.009, Lng: courier.Location.Lng}
+	clk.Add(100 * time.Second)
+
+	_, err := couriers.UpdatePosition(ctx, courier.ID, models.Position{Location: far, Timestamp: start.Add(10 * time.Second)})
+	if !errors.Is(err, service.ErrSpeedExceeded) {
+		t.Fatalf("UpdatePosition() error = %v, want %v", err, service.ErrSpeedExceeded)
+	}
+
+	// время устройства из будущего ограничивается временем сервера
+	got, err := couriers.UpdatePosition(ctx, courier.ID, models.Position{Location: far, Timestamp: start.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("UpdatePosition() error = %v", err)
+	}
+
+	if !got.UpdatedAt.Equal(clk.Now()) {
+		t.Errorf("UpdatedAt = %v, want %v", got.UpdatedAt, clk.Now())
+	}
+}
+
+func TestBlockedMoveReportsViolation(t *testing.T) {
+	// запрещенная зона начинается сразу к востоку от точки по умолчанию
+	specs := append([]geo.ZoneSpec{
+		{ID: "park", Geometry: geo.Geometry{Type: geo.GeometryPolygon, Coordinates: []byte(`[[[30.3615,59.92],[30.37,59.92],[30.37,59.94],[30.3615,59.94],[30.3615,59.92]]]`)}},
+	}, testutil.CityZones...)
+
+	tests := []struct {
+		name string
+		move func(ctx context.Context, couriers service.Courierer, courier *models.Courier) (models.Point, error)
+	}{
+		{
+			name: "move",
+			move: func(ctx context.Context, couriers service.Courierer, courier *models.Courier) (models.Point, error) {
+				_, err := couriers.MoveCourier(ctx, courier.ID, service.DirectionRight, 14)
+				return models.Point{Lat: courier.Location.Lat, Lng: courier.Location.Lng + 0.001}, err
+			},
+		},
+		{
+			name: "position",
+			move: func(ctx context.Context, couriers service.Courierer, courier *models.Courier) (models.Point, error) {
+				target := models.Point{Lat: courier.Location.Lat, Lng: 30.365}
+				_, err := couriers.UpdatePosition(ctx, courier.ID, models.Position{Location: target})
+				return target, err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			sink := events.NewChanSink(10)
+			couriers, clk := newTestCourierService(t, testutil.CourierOptions{Geofence: sink}, specs...)
+			courier := testutil.IdleCourier(t, couriers, models.VehicleFoot)
+			clk.Add(time.Minute)
+
+			target, err := tt.move(ctx, couriers, courier)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := couriers.GetCourier(ctx, courier.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Location.Lng >= 30.3615 {
+				t.Errorf("courier entered disabled zone at %v", got.Location)
+			}
+
+			select {
+			case event := <-sink.Events():
+				if event.Type != models.GeofenceViolation || event.ZoneID != "park" || event.Location != target {
+					t.Errorf("event = %+v, want violation of park at %v", event, target)
+				}
+			default:
+				t.Fatal("no geofence event published")
+			}
+
+			select {
+			case event := <-sink.Events():
+				t.Errorf("unexpected event %+v", event)
+			default:
+			}
+		})
+	}
+}
+
+func TestExpirePresenceReleasesCouriersWithoutOrders(t *testing.T) {
+	ctx := context.Background()
+	env := testutil.NewEnv(t)
+	couriers := env.Couriers(testutil.CourierOptions{})
+
+	assigned := testutil.IdleCourier(t, couriers, models.VehicleBicycle)
+	_, err := couriers.Transition(ctx, assigned.ID, models.CourierAssigned, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	carrying := testutil.IdleCourier(t, couriers, models.VehicleBicycle)
+	_, err = couriers.PickUp(ctx, carrying.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{assigned.ID, carrying.ID} {
+		err = couriers.Heartbeat(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expire := func() int {
+		t.Helper()
+
+		env.Clock.Add(2 * service.DefaultPresenceTTL)
+		env.Redis.FastForward(2 * service.DefaultPresenceTTL)
+
+		expired, err := couriers.ExpirePresence(ctx)
+		if err != nil {
+			t.Fatalf("ExpirePresence() error = %v", err)
+		}
+
+		return expired
+	}
+
+	if got := expire(); got != 2 {
+		t.Errorf("ExpirePresence() = %d, want 2", got)
+	}
+
+	got, err := couriers.GetCourier(ctx, assigned.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.State != models.CourierOffline {
+		t.Errorf("assigned courier state = %s, want %s", got.State, models.CourierOffline)
+	}
+
+	got, err = couriers.GetCourier(ctx, carrying.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.State == models.CourierOffline {
+		t.Errorf("carrying courier went offline")
+	}
+
+	// после доставки курьер с заказом проверяется снова по сохраненному heartbeat
+	_, err = couriers.DropOff(ctx, carrying.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := expire(); got != 1 {
+		t.Errorf("ExpirePresence() after drop off = %d, want 1", got)
+	}
+
+	got, err = couriers.GetCourier(ctx, carrying.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.State != models.CourierOffline {
+		t.Errorf("courier state after drop off = %s, want %s", got.State, models.CourierOffline)
+	}
+
+	if got := expire(); got != 0 {
+		t.Errorf("ExpirePresence() of forgotten couriers = %d, want 0", got)
+	}
+}
+
+func TestGeofenceEventsForEveryZone(t *testing.T) {
+	// к востоку от точки по умолчанию лежат вторая разрешенная зона и район, который только задает атрибуты
+	east := geo.Geometry{Type: geo.GeometryPolygon, Coordinates: []byte(`[[[30.3615,59.92],[30.37,59.92],[30.37,59.94],[30.3615,59.94],[30.3615,59.92]]]`)}
+	specs := append([]geo.ZoneSpec{
+		{ID: "east", Allowed: true, Geometry: east},
+		{ID: "district", Overlay: true, Geometry: east, Attributes: geo.Attributes{geo.AttributePriceMultiplier: 2}},
+	}, testutil.CityZones...)
+
+	ctx := context.Background()
+	sink := events.NewChanSink(10)
+	couriers, clk := newTestCourierService(t, testutil.CourierOptions{Geofence: sink}, specs...)
+	courier := testutil.IdleCourier(t, couriers, models.VehicleFoot)
+
+	// события каждого перемещения по id зоны
+	move := func(lng float64) map[string]models.GeofenceEventType {
+		t.Helper()
+
+		clk.Add(time.Minute)
+		_, err := couriers.UpdatePosition(ctx, courier.ID, models.Position{Location: models.Point{Lat: courier.Location.Lat, Lng: lng}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := make(map[string]models.GeofenceEventType)
+		for {
+			select {
+			case event := <-sink.Events():
+				got[event.ZoneID] = event.Type
+			default:
+				return got
+			}
+		}
+	}
+
+	got := move(30.365)
+	want := map[string]models.GeofenceEventType{"east": models.GeofenceEnter, "district": models.GeofenceEnter}
+	if len(got) != len(want) || got["east"] != want["east"] || got["district"] != want["district"] {
+		t.Errorf("events on enter = %v, want %v", got, want)
+	}
+
+	got = move(courier.Location.Lng)
+	want = map[string]models.GeofenceEventType{"east": models.GeofenceExit, "district": models.GeofenceExit}
+	if len(got) != len(want) || got["east"] != want["east"] || got["district"] != want["district"] {
+		t.Errorf("events on exit = %v, want %v", got, want)
+	}
+}
+
+func TestNearbyCouriers(t *testing.T) {
+	ctx := context.Background()
+	couriers, clk := newTestCourierService(t, testutil.CourierOptions{})
+
+	// курьеры примерно в 1 км, 100 м и 300 м к северу от точки
+	point := models.Point{Lat: 59.9, Lng: 30.3}
+	offsets := []float64{0.009, 0.0009, 0.0027}
+	ids := make([]string, 0, len(offsets))
+	for _, offset := range offsets {
+		courier := testutil.IdleCourier(t, couriers, models.VehicleCar)
+		ids = append(ids, courier.ID)
+
+		clk.Add(time.Hour)
+		_, err := couriers.UpdatePosition(ctx, courier.ID, models.Position{Location: models.Point{Lat: point.Lat + offset, Lng: point.Lng}})
+		if err != nil {
+			t.Fatalf("UpdatePosition() error = %v", err)
+		}
+	}
+	far, near, middle := ids[0], ids[1], ids[2]
+
+	tests := []struct {
+		name   string
+		radius float64
+		limit  int
+		want   []string
+	}{
+		{name: "nearest", limit: 2, want: []string{near, middle}},
+		{name: "nearest without limit", want: []string{near, middle, far}},
+		{name: "radius", radius: 500, want: []string{near, middle}},
+		{name: "radius with limit", radius: 500, limit: 1, want: []string{near}},
+		{name: "radius larger than limit", radius: 2000, limit: 5, want: []string{near, middle, far}},
+		{name: "nobody in radius", radius: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := couriers.NearbyCouriers(ctx, point, tt.radius, tt.limit)
+			if err != nil {
+				t.Fatalf("NearbyCouriers() error = %v", err)
+			}
+
+			gotIDs := make([]string, 0, len(got))
+			for i := range got {
+				gotIDs = append(gotIDs, got[i].ID)
+			}
+			if len(gotIDs) != len(tt.want) {
+				t.Fatalf("NearbyCouriers() = %v, want %v", gotIDs, tt.want)
+			}
+			for i := range gotIDs {
+				if gotIDs[i] != tt.want[i] {
+					t.Fatalf("NearbyCouriers() = %v, want %v", gotIDs, tt.want)
+				}
+			}
+		})
+	}
+
+	// удаленный курьер пропадает из поиска
+	err := couriers.DeleteCourier(ctx, near)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := couriers.NearbyCouriers(ctx, point, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != middle {
+		t.Errorf("NearbyCouriers() after delete = %+v, want courier %s", got, middle)
+	}
+}
+
+func TestCreateAndDeleteCouriers(t *testing.T) {
+	ctx := context.Background()
+	couriers, _ := newTestCourierService(t, testutil.CourierOptions{})
+
+	created := make(map[string]models.Vehicle)
+	ids := make([]string, 0, 3)
+	for _, vehicle := range []models.Vehicle{models.VehicleFoot, models.VehicleBicycle, models.VehicleCar} {
+		courier, err := couriers.CreateCourier(ctx, vehicle)
+		if err != nil {
+			t.Fatalf("CreateCourier(%s) error = %v", vehicle, err)
+		}
+		if _, ok := created[courier.ID]; ok {
+			t.Fatalf("CreateCourier(%s) id = %s, already used", vehicle, courier.ID)
+		}
+		created[courier.ID] = vehicle
+		ids = append(ids, courier.ID)
+	}
+
+	// каждый курьер хранится отдельно под своим id, изменение одного не меняет остальных
+	scored := ids[1]
+	_, err := couriers.AddScore(ctx, scored, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := couriers.ListCouriers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != len(created) {
+		t.Fatalf("ListCouriers() = %d couriers, want %d", len(list), len(created))
+	}
+	for i := range list {
+		vehicle, ok := created[list[i].ID]
+		if !ok || list[i].Vehicle != vehicle {
+			t.Errorf("ListCouriers()[%d] = %s on %s, want one of created couriers", i, list[i].ID, list[i].Vehicle)
+		}
+
+		wantScore := 0
+		if list[i].ID == scored {
+			wantScore = 5
+		}
+		if list[i].Score != wantScore {
+			t.Errorf("courier %s score = %d, want %d", list[i].ID, list[i].Score, wantScore)
+		}
+	}
+
+	err = couriers.DeleteCourier(ctx, scored)
+	if err != nil {
+		t.Fatalf("DeleteCourier() error = %v", err)
+	}
+
+	_, err = couriers.GetCourier(ctx, scored)
+	if !errors.Is(err, service.ErrCourierNotFound) {
+		t.Errorf("GetCourier() after delete error = %v, want %v", err, service.ErrCourierNotFound)
+	}
+	exists, err := couriers.Exists(ctx, scored)
+	if err != nil || exists {
+		t.Errorf("Exists() after delete = %v, %v, want false", exists, err)
+	}
+
+	err = couriers.DeleteCourier(ctx, scored)
+	if !errors.Is(err, service.ErrCourierNotFound) {
+		t.Errorf("second DeleteCourier() error = %v, want %v", err, service.ErrCourierNotFound)
+	}
+
+	list, err = couriers.ListCouriers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != len(created)-1 {
+		t.Fatalf("ListCouriers() after delete = %d couriers, want %d", len(list), len(created)-1)
+	}
+	for i := range list {
+		if list[i].ID == scored {
+			t.Errorf("ListCouriers() after delete contains courier %s", scored)
+		}
+
+		got, err := couriers.GetCourier(ctx, list[i].ID)
+		if err != nil || got.ID != list[i].ID {
+			t.Errorf("GetCourier(%s) = %+v, %v, want the remaining courier", list[i].ID, got, err)
+		}
+	}
+}
@@ -4,15 +4,45 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"github.com/GoGerman/geo-task/cache"
 	"github.com/GoGerman/geo-task/module/courier/models"
 	"github.com/redis/go-redis/v9"
+	"sort"
 )
 
-const CourierKey = "courier"
+const CourierIDKey = "couriers:id"
+const CouriersKey = "couriers"
+const CouriersGeoKey = "couriers:geo"
+
+// nearestSearchRadius радиус поиска ближайших курьеров в км, покрывает всю поверхность Земли
+const nearestSearchRadius = 20100
+
+// maxUpdateAttempts количество попыток изменить курьера, если он параллельно менялся другим запросом
+const maxUpdateAttempts = 100
+
+var (
+	// ErrNotModified возвращается из UpdateFunc, если курьера не нужно сохранять
+	ErrNotModified = errors.New("courier not modified")
+	// ErrUpdateConflict курьер менялся параллельно во всех попытках изменения
+	ErrUpdateConflict = errors.New("courier update conflict")
+)
+
+// UpdateFunc изменяет курьера и возвращает переходы между состояниями, которые сохраняются вместе с ним.
+// При параллельном изменении курьера функция вызывается повторно со свежим курьером, поэтому она не должна иметь побочных эффектов
+type UpdateFunc func(courier *models.Courier) ([]models.StateTransition, error)
 
 type CourierStorager interface {
-	Save(ctx context.Context, courier models.Courier) error // сохранить курьера по ключу courier
-	GetOne(ctx context.Context) (*models.Courier, error)    // получить курьера по ключу courier
+	Save(ctx context.Context, courier models.Courier) error                                               // сохранить курьера по ключу courier:{id} и добавить его в множество couriers
+	GetByID(ctx context.Context, id string) (*models.Courier, error)                                      // получить курьера по id, nil если курьера нет
+	Exists(ctx context.Context, id string) (bool, error)                                                  // проверить, что курьер есть, не читая его
+	List(ctx context.Context) ([]models.Courier, error)                                                   // получить всех активных курьеров
+	Delete(ctx context.Context, id string) (bool, error)                                                  // удалить курьера, false если курьера не было
+	GenerateUniqueID(ctx context.Context) (int64, error)                                                  // сгенерировать уникальный id
+	GetByRadius(ctx context.Context, lng, lat, radius float64, unit string) ([]models.Courier, error)     // получить курьеров в радиусе от точки, ближайшие первыми
+	Update(ctx context.Context, id string, fn UpdateFunc, steps ...cache.TxStep) (*models.Courier, error) // атомарно изменить курьера функцией fn и сохранить его вместе с переходами и шагами steps, nil если курьера нет
+	Transitions(ctx context.Context, id string) ([]models.StateTransition, error)                         // получить историю смены состояний курьера
+	Nearest(ctx context.Context, lng, lat float64, k int) ([]models.Courier, error)                       // получить k ближайших к точке курьеров
+	Unindex(ctx context.Context, id string) error                                                         // убрать курьера из гео индекса до следующего сохранения
 }
 
 type CourierStorage struct {
@@ -23,12 +53,45 @@ func NewCourierStorage(storage *redis.Client) CourierStorager {
 	return &CourierStorage{storage: storage}
 }
 
-func (s CourierStorage) GetOne(ctx context.Context) (*models.Courier, error) {
+// CourierKey возвращает ключ, по которому хранится курьер
+func CourierKey(id string) string {
+	return "courier:" + id
+}
+
+// StatesKey возвращает ключ стрима с историей состояний курьера
+func StatesKey(id string) string {
+	return CourierKey(id) + ":states"
+}
+
+// statesMaxLen приблизительное ограничение длины истории состояний одного курьера
+const statesMaxLen = 1000
+
+// decodeCourier десериализует курьера, курьеры, сохраненные до появления состояний и транспорта,
+// считаются свободными пешими курьерами
+func decodeCourier(data []byte) (*models.Courier, error) {
 	var courier models.Courier
+
+	err := json.Unmarshal(data, &courier)
+	if err != nil {
+		return nil, err
+	}
+
+	if courier.State == "" {
+		courier.State = models.CourierIdle
+	}
+
+	if courier.Vehicle == "" {
+		courier.Vehicle = models.DefaultVehicle
+	}
+
+	return &courier, nil
+}
+
+func (s CourierStorage) GetByID(ctx context.Context, id string) (*models.Courier, error) {
 	var data []byte
 	var err error
 
-	data, err = s.storage.Get(ctx, CourierKey).Bytes()
+	data, err = s.storage.Get(ctx, CourierKey(id)).Bytes()
 	if errors.Is(err, redis.Nil) {
 		return nil, nil
 	}
@@ -36,21 +99,262 @@ func (s CourierStorage) GetOne(ctx context.Context) (*models.Courier, error) {
 		return nil, err
 	}
 
-	err = json.Unmarshal(data, &courier)
+	return decodeCourier(data)
+}
+
+func (s CourierStorage) Exists(ctx context.Context, id string) (bool, error) {
+	n, err := s.storage.Exists(ctx, CourierKey(id)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func (s CourierStorage) List(ctx context.Context) ([]models.Courier, error) {
+	// id активных курьеров хранятся в множестве couriers, сами курьеры - по ключам courier:{id}
+	ids, err := s.storage.SMembers(ctx, CouriersKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	couriers, err := s.getByIDs(ctx, ids)
 	if err != nil {
 		return nil, err
 	}
 
-	return &courier, nil
+	// порядок элементов множества не определен, сортируем для стабильного результата
+	sort.Slice(couriers, func(i, j int) bool {
+		return couriers[i].ID < couriers[j].ID
+	})
+
+	return couriers, nil
+}
+
+func (s CourierStorage) GetByRadius(ctx context.Context, lng, lat, radius float64, unit string) ([]models.Courier, error) {
+	ids, err := s.storage.GeoSearch(ctx, CouriersGeoKey, &redis.GeoSearchQuery{
+		Longitude:  lng,
+		Latitude:   lat,
+		Radius:     radius,
+		RadiusUnit: unit,
+		Sort:       "ASC",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getByIDs(ctx, ids)
+}
+
+func (s CourierStorage) Nearest(ctx context.Context, lng, lat float64, k int) ([]models.Courier, error) {
+	ids, err := s.storage.GeoSearch(ctx, CouriersGeoKey, &redis.GeoSearchQuery{
+		Longitude:  lng,
+		Latitude:   lat,
+		Radius:     nearestSearchRadius,
+		RadiusUnit: "km",
+		Sort:       "ASC",
+		Count:      k,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getByIDs(ctx, ids)
+}
+
+// getByIDs получает курьеров по списку id с сохранением порядка, отсутствующие курьеры пропускаются
+func (s CourierStorage) getByIDs(ctx context.Context, ids []string) ([]models.Courier, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(ids))
+	for i := range ids {
+		keys = append(keys, CourierKey(ids[i]))
+	}
+
+	values, err := s.storage.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	couriers := make([]models.Courier, 0, len(values))
+	for i := range values {
+		// ключ мог быть удален между чтением множества и курьеров
+		data, ok := values[i].(string)
+		if !ok {
+			continue
+		}
+
+		courier, err := decodeCourier([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+
+		couriers = append(couriers, *courier)
+	}
+
+	return couriers, nil
 }
 
 func (s CourierStorage) Save(ctx context.Context, courier models.Courier) error {
+	_, err := s.storage.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		s.save(ctx, pipe, courier)
+		return nil
+	})
+
+	return err
+}
+
+// Update читает курьера под WATCH и сохраняет результат fn в транзакции MULTI/EXEC.
+// Если курьера или ключи шагов изменили между чтением и сохранением, попытка повторяется со свежими данными.
+// Шаги steps проверяются до вызова fn и выполняются в той же транзакции, например закрепление заказа за курьером
+func (s CourierStorage) Update(ctx context.Context, id string, fn UpdateFunc, steps ...cache.TxStep) (*models.Courier, error) {
+	key := CourierKey(id)
+
+	keys := []string{key}
+	for i := range steps {
+		keys = append(keys, steps[i].Keys()...)
+	}
+
+	for i := 0; i < maxUpdateAttempts; i++ {
+		var courier *models.Courier
+
+		err := s.storage.Watch(ctx, func(tx *redis.Tx) error {
+			data, err := tx.Get(ctx, key).Bytes()
+			if errors.Is(err, redis.Nil) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			courier, err = decodeCourier(data)
+			if err != nil {
+				return err
+			}
+
+			for i := range steps {
+				err = steps[i].Check(ctx, tx)
+				if err != nil {
+					return err
+				}
+			}
+
+			transitions, err := fn(courier)
+			if err != nil {
+				return err
+			}
+
+			values := make([][]byte, 0, len(transitions))
+			for i := range transitions {
+				data, err := json.Marshal(transitions[i])
+				if err != nil {
+					return err
+				}
+				values = append(values, data)
+			}
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				s.save(ctx, pipe, *courier)
+				for i := range values {
+					pipe.XAdd(ctx, &redis.XAddArgs{
+						Stream: StatesKey(courier.ID),
+						MaxLen: statesMaxLen,
+						Approx: true,
+						Values: map[string]interface{}{
+							"transition": values[i],
+						},
+					})
+				}
+				for i := range steps {
+					steps[i].Queue(ctx, pipe)
+				}
+				return nil
+			})
+
+			return err
+		}, keys...)
 
-	_, err := s.storage.Set(ctx, CourierKey, courier, 0).Result()
+		switch {
+		case errors.Is(err, redis.TxFailedErr):
+			continue
+		case errors.Is(err, ErrNotModified):
+			return courier, nil
+		case err != nil:
+			return nil, err
+		}
 
+		return courier, nil
+	}
+
+	return nil, ErrUpdateConflict
+}
+
+func (s CourierStorage) Transitions(ctx context.Context, id string) ([]models.StateTransition, error) {
+	messages, err := s.storage.XRange(ctx, StatesKey(id), "-", "+").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	transitions := make([]models.StateTransition, 0, len(messages))
+	for i := range messages {
+		data, ok := messages[i].Values["transition"].(string)
+		if !ok {
+			continue
+		}
+
+		var transition models.StateTransition
+		err = json.Unmarshal([]byte(data), &transition)
+		if err != nil {
+			return nil, err
+		}
+
+		transitions = append(transitions, transition)
+	}
+
+	return transitions, nil
+}
+
+// save добавляет в pipeline сохранение курьера, его id в множество активных и координаты в гео индекс
+func (s CourierStorage) save(ctx context.Context, pipe redis.Pipeliner, courier models.Courier) {
+	pipe.Set(ctx, CourierKey(courier.ID), courier, 0)
+	pipe.SAdd(ctx, CouriersKey, courier.ID)
+
+	// курьер offline не должен находиться поиском ближайших
+	if courier.State == models.CourierOffline {
+		pipe.ZRem(ctx, CouriersGeoKey, courier.ID)
+		return
+	}
+
+	// гео индекс курьеров для поиска ближайших, обновляется при каждом сохранении
+	pipe.GeoAdd(ctx, CouriersGeoKey, &redis.GeoLocation{
+		Name:      courier.ID,
+		Longitude: courier.Location.Lng,
+		Latitude:  courier.Location.Lat,
+	})
+}
+
+func (s CourierStorage) Unindex(ctx context.Context, id string) error {
+	return s.storage.ZRem(ctx, CouriersGeoKey, id).Err()
+}
+
+func (s CourierStorage) Delete(ctx context.Context, id string) (bool, error) {
+	var deleted *redis.IntCmd
+
+	_, err := s.storage.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		deleted = pipe.Del(ctx, CourierKey(id))
+		pipe.SRem(ctx, CouriersKey, id)
+		pipe.ZRem(ctx, CouriersGeoKey, id)
+		return nil
+	})
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	return nil
+	return deleted.Val() > 0, nil
+}
+
+func (s CourierStorage) GenerateUniqueID(ctx context.Context) (int64, error) {
+	return s.storage.Incr(ctx, CourierIDKey).Result()
 }
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// HeartbeatsKey sorted set с временем последнего heartbeat курьеров, score - unix время
+const HeartbeatsKey = "couriers:heartbeats"
+
+type PresenceStorager interface {
+	Touch(ctx context.Context, courierID string, now time.Time, ttl time.Duration) error // продлить ключ присутствия курьера и записать время heartbeat
+	Expired(ctx context.Context, before time.Time) ([]string, error)                     // получить курьеров без heartbeat с момента before, ключ присутствия которых истек
+	Forget(ctx context.Context, courierID string) error                                  // перестать отслеживать присутствие курьера
+}
+
+// PresenceStorage ключ присутствия courier:{id}:presence живет ttl после последнего heartbeat,
+// а время heartbeat дублируется в sorted set, чтобы не перебирать ключи всех курьеров
+type PresenceStorage struct {
+	storage *redis.Client
+}
+
+func NewPresenceStorage(storage *redis.Client) PresenceStorager {
+	return &PresenceStorage{storage: storage}
+}
+
+// PresenceKey возвращает ключ присутствия курьера
+func PresenceKey(courierID string) string {
+	return CourierKey(courierID) + ":presence"
+}
+
+func (s *PresenceStorage) Touch(ctx context.Context, courierID string, now time.Time, ttl time.Duration) error {
+	_, err := s.storage.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, PresenceKey(courierID), now.Unix(), ttl)
+		pipe.ZAdd(ctx, HeartbeatsKey, redis.Z{
+			Score:  float64(now.Unix()),
+			Member: courierID,
+		})
+		return nil
+	})
+
+	return err
+}
+
+func (s *PresenceStorage) Expired(ctx context.Context, before time.Time) ([]string, error) {
+	ids, err := s.storage.ZRangeByScore(ctx, HeartbeatsKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: "(" + strconv.FormatInt(before.Unix(), 10),
+	}).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	cmds := make([]*redis.IntCmd, 0, len(ids))
+	_, err = s.storage.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i := range ids {
+			cmds = append(cmds, pipe.Exists(ctx, PresenceKey(ids[i])))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// решение принимает ключ присутствия: heartbeat на другом инстансе мог продлить его после чтения sorted set
+	expired := make([]string, 0, len(ids))
+	for i := range cmds {
+		if cmds[i].Val() == 0 {
+			expired = append(expired, ids[i])
+		}
+	}
+
+	return expired, nil
+}
+
+func (s *PresenceStorage) Forget(ctx context.Context, courierID string) error {
+	return s.storage.ZRem(ctx, HeartbeatsKey, courierID).Err()
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/GoGerman/geo-task/module/courier/models"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+const (
+	// trackMaxLen приблизительное ограничение длины трека одного курьера
+	trackMaxLen = 10000
+	// trackRetention время хранения трека после последнего перемещения курьера
+	trackRetention = 7 * 24 * time.Hour
+	// maxStreamSeq наибольший номер записи стрима в пределах одной миллисекунды
+	maxStreamSeq = "18446744073709551615"
+)
+
+// appendPointScript добавляет точку в стрим KEYS[1] с id из времени точки ARGV[1] в миллисекундах.
+// Точка не может встать раньше последней записи стрима, например при повторе симуляции с того же времени,
+// поэтому время id не меньше времени последней записи.
+// ARGV[2] - точка, ARGV[3] - ограничение длины стрима, ARGV[4] - время хранения стрима в секундах
+var appendPointScript = redis.NewScript(`
+local ms = ARGV[1]
+local last = redis.call('XREVRANGE', KEYS[1], '+', '-', 'COUNT', 1)
+if last[1] then
+	local lastMs = string.match(last[1][1], '^(%d+)')
+	if tonumber(lastMs) > tonumber(ms) then
+		ms = lastMs
+	end
+end
+redis.call('XADD', KEYS[1], 'MAXLEN', '~', ARGV[3], ms .. '-*', 'point', ARGV[2])
+redis.call('EXPIRE', KEYS[1], ARGV[4])
+return redis.status_reply('OK')
+`)
+
+type TrackStorager interface {
+	Append(ctx context.Context, courierID string, point models.TrackPoint) error                  // добавить точку в трек курьера
+	Range(ctx context.Context, courierID string, from, to time.Time) ([]models.TrackPoint, error) // получить точки трека за период, нулевое время - без ограничения
+}
+
+// TrackStorage хранит трек каждого курьера в отдельном redis stream.
+// id записи задается временем точки, поэтому точки за период выбираются по id записей
+type TrackStorage struct {
+	storage *redis.Client
+}
+
+func NewTrackStorage(storage *redis.Client) TrackStorager {
+	return &TrackStorage{storage: storage}
+}
+
+// TrackKey возвращает ключ стрима с треком курьера
+func TrackKey(courierID string) string {
+	return CourierKey(courierID) + ":track"
+}
+
+func (s *TrackStorage) Append(ctx context.Context, courierID string, point models.TrackPoint) error {
+	data, err := json.Marshal(point)
+	if err != nil {
+		return err
+	}
+
+	// трек удаленного или неактивного курьера удаляется по истечению срока хранения
+	return appendPointScript.Run(ctx, s.storage, []string{TrackKey(courierID)},
+		point.Timestamp.UnixMilli(), data, trackMaxLen, int64(trackRetention/time.Second)).Err()
+}
+
+func (s *TrackStorage) Range(ctx context.Context, courierID string, from, to time.Time) ([]models.TrackPoint, error) {
+	start, end := "-", "+"
+	if !from.IsZero() {
+		start = strconv.FormatInt(from.UnixMilli(), 10) + "-0"
+	}
+	if !to.IsZero() {
+		end = strconv.FormatInt(to.UnixMilli(), 10) + "-" + maxStreamSeq
+	}
+
+	messages, err := s.storage.XRange(ctx, TrackKey(courierID), start, end).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]models.TrackPoint, 0, len(messages))
+	for i := range messages {
+		data, ok := messages[i].Values["point"].(string)
+		if !ok {
+			continue
+		}
+
+		var point models.TrackPoint
+		err = json.Unmarshal([]byte(data), &point)
+		if err != nil {
+			return nil, err
+		}
+
+		points = append(points, point)
+	}
+
+	return points, nil
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"github.com/GoGerman/geo-task/module/courier/models"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T) *redis.Client {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	rclient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rclient.Close() })
+
+	return rclient
+}
+
+func TestTrackRangeByPointTimestamp(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	tracks := NewTrackStorage(client)
+
+	// время симуляции не совпадает с системным временем redis, id записей задаются временем точек
+	start := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		err := tracks.Append(ctx, "1", models.TrackPoint{
+			Location:  models.Point{Lat: 59.9 + float64(i)*0.001, Lng: 30.3},
+			Timestamp: start.Add(time.Duration(i) * time.Minute),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     int
+	}{
+		{name: "without bounds", want: 5},
+		{name: "from", from: start.Add(2 * time.Minute), want: 3},
+		{name: "to", to: start.Add(time.Minute), want: 2},
+		{name: "period", from: start.Add(time.Minute), to: start.Add(3 * time.Minute), want: 3},
+		{name: "before track", to: start.Add(-time.Minute), want: 0},
+		{name: "after track", from: start.Add(time.Hour), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, err := tracks.Range(ctx, "1", tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Range() error = %v", err)
+			}
+
+			if len(points) != tt.want {
+				t.Fatalf("Range() = %d points, want %d", len(points), tt.want)
+			}
+
+			for i := range points {
+				if !tt.from.IsZero() && points[i].Timestamp.Before(tt.from) || !tt.to.IsZero() && points[i].Timestamp.After(tt.to) {
+					t.Errorf("Range() point %v outside of period", points[i].Timestamp)
+				}
+			}
+		})
+	}
+
+	messages, err := client.XRange(ctx, TrackKey("1"), "-", "+").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 5 {
+		t.Fatalf("stream has %d entries, want 5", len(messages))
+	}
+	for i := range messages {
+		want := strconv.FormatInt(start.Add(time.Duration(i)*time.Minute).UnixMilli(), 10) + "-0"
+		if messages[i].ID != want {
+			t.Errorf("entry %d id = %s, want %s", i, messages[i].ID, want)
+		}
+	}
+}
+
+func TestTrackAppendBeforeLastPoint(t *testing.T) {
+	ctx := context.Background()
+	tracks := NewTrackStorage(newTestClient(t))
+
+	// повтор симуляции с того же времени пишет точки раньше уже записанных
+	start := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{start.Add(time.Minute), start, start.Add(time.Minute)} {
+		err := tracks.Append(ctx, "1", models.TrackPoint{Location: models.Point{Lat: 59.9, Lng: 30.3}, Timestamp: at})
+		if err != nil {
+			t.Fatalf("Append(%v) error = %v", at, err)
+		}
+	}
+
+	points, err := tracks.Range(ctx, "1", start.Add(time.Minute), start.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 3 {
+		t.Fatalf("Range() = %d points, want 3", len(points))
+	}
+	if !points[1].Timestamp.Equal(start) {
+		t.Errorf("second point timestamp = %v, want %v", points[1].Timestamp, start)
+	}
+}
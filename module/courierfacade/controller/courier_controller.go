@@ -3,12 +3,35 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"github.com/GoGerman/geo-task/module/courier/models"
+	cservice "github.com/GoGerman/geo-task/module/courier/service"
 	"github.com/GoGerman/geo-task/module/courierfacade/service"
 	"github.com/gin-gonic/gin"
 	"log"
+	"net/http"
 	"time"
 )
 
+// CourierIDParam параметр запроса с id курьера, которым управляет вкладка браузера
+const CourierIDParam = "courier_id"
+
+// типы сообщений WebSocket
+const (
+	MessageMove     = "move"     // шаг курьера с клавиатуры демо-карты
+	MessagePosition = "position" // абсолютное положение с телефона курьера
+)
+
+// CourierPosition положение курьера с устройства
+type CourierPosition struct {
+	Lat       float64   `json:"lat"`
+	Lng       float64   `json:"lng"`
+	Accuracy  float64   `json:"accuracy"`  // точность в метрах
+	Heading   float64   `json:"heading"`   // направление движения в градусах от севера
+	Speed     float64   `json:"speed"`     // скорость в м/с
+	Timestamp time.Time `json:"timestamp"` // время на устройстве в формате RFC3339
+}
+
 type CourierController struct {
 	courierService service.CourierFacer
 }
@@ -21,12 +44,70 @@ func (c *CourierController) GetStatus(ctx *gin.Context) {
 	// установить задержку в 50 миллисекунд
 	time.Sleep(time.Millisecond * 50)
 
+	courierID := ctx.Query(CourierIDParam)
+	if courierID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": CourierIDParam + " is required"})
+		return
+	}
+
 	// получить статус курьера из сервиса courierService используя метод GetStatus
 	// отправить статус курьера в ответ
-	ctx.JSON(200, c.courierService.GetStatus(ctx))
+	status, err := c.courierService.GetStatus(ctx, courierID)
+	if errors.Is(err, cservice.ErrCourierNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, status)
+}
+
+// HandleMessage направляет сообщение WebSocket обработчику по его типу
+func (c *CourierController) HandleMessage(courierID string, m webSocketMessage) {
+	c.heartbeat(courierID)
+
+	switch m.Name {
+	case MessagePosition:
+		c.UpdatePosition(courierID, m)
+	default:
+		// сообщения других типов обрабатываются как шаг, как и до появления типа position
+		c.MoveCourier(courierID, m)
+	}
+}
+
+func (c *CourierController) UpdatePosition(courierID string, m webSocketMessage) {
+	var cp CourierPosition
+
+	v, ok := m.Data.([]byte)
+	if !ok {
+		return
+	}
+
+	err := json.Unmarshal(v, &cp)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err = c.courierService.UpdatePosition(context.Background(), courierID, models.Position{
+		Location: models.Point{
+			Lat: cp.Lat,
+			Lng: cp.Lng,
+		},
+		Accuracy:  cp.Accuracy,
+		Heading:   cp.Heading,
+		Speed:     cp.Speed,
+		Timestamp: cp.Timestamp,
+	})
+	if err != nil {
+		log.Printf("error while updating courier %s position: %v", courierID, err)
+	}
 }
 
-func (c *CourierController) MoveCourier(m webSocketMessage) {
+func (c *CourierController) MoveCourier(courierID string, m webSocketMessage) {
 	var cm CourierMove
 	var err error
 	// получить данные из m.Data и десериализовать их в структуру CourierMove
@@ -46,5 +127,5 @@ func (c *CourierController) MoveCourier(m webSocketMessage) {
 
 	// вызвать метод MoveCourier у courierService
 	ctx := context.Background()
-	c.courierService.MoveCourier(ctx, cm.Direction, cm.Zoom)
+	c.courierService.MoveCourier(ctx, courierID, cm.Direction, cm.Zoom)
 }
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+	"log"
+	"time"
+)
+
+const (
+	// pingPeriod интервал ping от сервера, браузер отвечает на ping автоматически,
+	// поэтому присутствие курьера продлевается, даже если он стоит на месте
+	pingPeriod = 20 * time.Second
+	// pongWait время ожидания сообщения или pong, после которого соединение считается разорванным
+	pongWait = 2*pingPeriod + 5*time.Second
+	// writeWait время на отправку ping
+	writeWait = 5 * time.Second
+)
+
+// heartbeat продлевает присутствие курьера, ошибка только логируется, чтобы не разрывать соединение
+func (c *CourierController) heartbeat(courierID string) {
+	err := c.courierService.Heartbeat(context.Background(), courierID)
+	if err != nil {
+		log.Printf("error while refreshing courier %s presence: %v", courierID, err)
+	}
+}
+
+// watchPongs продлевает присутствие курьера при каждом pong. Если pong и сообщения не приходят дольше pongWait,
+// чтение из соединения завершается с ошибкой. Вызывается до начала чтения: обработчик pong
+// выполняется в горутине, которая читает соединение, и меняться во время чтения не должен
+func (c *CourierController) watchPongs(conn *websocket.Conn, courierID string) {
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		c.heartbeat(courierID)
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+}
+
+// keepAlive отправляет ping до закрытия done. Горутина только пишет управляющие сообщения,
+// WriteControl можно вызывать параллельно с чтением
+func (c *CourierController) keepAlive(conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait))
+			if err != nil {
+				return
+			}
+		}
+	}
+}
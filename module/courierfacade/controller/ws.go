@@ -3,9 +3,12 @@ package controller
 import (
 	"encoding/json"
 	"fmt"
+	cservice "github.com/GoGerman/geo-task/module/courier/service"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"log"
+	"net/http"
+	"time"
 )
 
 /**
@@ -13,12 +16,41 @@ import (
  */
 
 func (c *CourierController) Websocket(ctx *gin.Context) {
+	// соединение управляет курьером, id которого передан при подключении
+	courierID := ctx.Query(CourierIDParam)
+	if courierID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": CourierIDParam + " is required"})
+		return
+	}
+
+	// heartbeat несуществующего курьера засорял бы множество присутствия, поэтому курьер проверяется до подключения
+	exists, err := c.courierService.Exists(ctx, courierID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !exists {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": cservice.ErrCourierNotFound.Error()})
+		return
+	}
+
 	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	handleConnection(conn, c.MoveCourier)
+	// курьер присутствует, пока открыт WebSocket: heartbeat при подключении, каждом сообщении и pong
+	c.heartbeat(courierID)
+	c.watchPongs(conn, courierID)
+
+	done := make(chan struct{})
+	defer close(done)
+	go c.keepAlive(conn, done)
+
+	handleConnection(conn, func(m webSocketMessage) {
+		_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.HandleMessage(courierID, m)
+	})
 }
 
 var upgrader = websocket.Upgrader{
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"context"
+	"github.com/GoGerman/geo-task/module/courier/models"
+	"github.com/GoGerman/geo-task/module/courierfacade/service"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// positionCall вызов UpdatePosition или MoveCourier фасада
+type positionCall struct {
+	courierID string
+	position  *models.Position
+	direction int
+	zoom      int
+}
+
+// recordingFacade фасад, который передает в канал перемещения курьеров
+type recordingFacade struct {
+	service.CourierFacer
+	calls chan positionCall
+}
+
+func (f recordingFacade) Exists(ctx context.Context, courierID string) (bool, error) {
+	return courierID == "1", nil
+}
+
+func (f recordingFacade) Heartbeat(ctx context.Context, courierID string) error {
+	return nil
+}
+
+func (f recordingFacade) UpdatePosition(ctx context.Context, courierID string, position models.Position) error {
+	f.calls <- positionCall{courierID: courierID, position: &position}
+	return nil
+}
+
+func (f recordingFacade) MoveCourier(ctx context.Context, courierID string, direction, zoom int) {
+	f.calls <- positionCall{courierID: courierID, direction: direction, zoom: zoom}
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, chan positionCall) {
+	t.Helper()
+
+	calls := make(chan positionCall, 10)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ws", NewCourierController(recordingFacade{calls: calls}).Websocket)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return server, calls
+}
+
+func dial(t *testing.T, server *httptest.Server, courierID string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?" + CourierIDParam + "=" + courierID
+
+	return websocket.DefaultDialer.Dial(url, nil)
+}
+
+func nextCall(t *testing.T, calls chan positionCall) positionCall {
+	t.Helper()
+
+	select {
+	case call := <-calls:
+		return call
+	case <-time.After(time.Second):
+		t.Fatal("message was not handled")
+	}
+
+	return positionCall{}
+}
+
+func TestWebsocketPositionMessage(t *testing.T) {
+	server, calls := newTestServer(t)
+
+	conn, _, err := dial(t, server, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	messages := []string{
+		// положение с неверными данными пропускается, соединение остается открытым
+		`{"name": "position", "data": {"lat": "north"}}`,
+		`{"name": "position", "data": {"lat": 59.93, "lng": 30.33, "accuracy": 5, "heading": 90, "speed": 1.5, "timestamp": "2024-01-01T12:00:00+03:00"}}`,
+		// сообщение без типа обрабатывается как шаг
+		`{"data": {"direction": 2, "zoom": 14}}`,
+	}
+	for _, message := range messages {
+		err = conn.WriteMessage(websocket.TextMessage, []byte(message))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	call := nextCall(t, calls)
+	if call.position == nil {
+		t.Fatalf("first call = %+v, want position", call)
+	}
+
+	want := models.Position{
+		Location:  models.Point{Lat: 59.93, Lng: 30.33},
+		Accuracy:  5,
+		Heading:   90,
+		Speed:     1.5,
+		Timestamp: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+	}
+	got := *call.position
+	if call.courierID != "1" || got.Location != want.Location || got.Accuracy != want.Accuracy ||
+		got.Heading != want.Heading || got.Speed != want.Speed || !got.Timestamp.Equal(want.Timestamp) {
+		t.Errorf("UpdatePosition(%s, %+v), want UpdatePosition(1, %+v)", call.courierID, got, want)
+	}
+
+	call = nextCall(t, calls)
+	if call.position != nil || call.direction != 2 || call.zoom != 14 {
+		t.Errorf("second call = %+v, want move with direction 2 and zoom 14", call)
+	}
+}
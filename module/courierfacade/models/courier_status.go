@@ -3,9 +3,12 @@ package models
 import (
 	cm "github.com/GoGerman/geo-task/module/courier/models"
 	om "github.com/GoGerman/geo-task/module/order/models"
+	sm "github.com/GoGerman/geo-task/module/shift/models"
 )
 
 type CourierStatus struct {
-	Courier cm.Courier `json:"courier"`
-	Orders  []om.Order `json:"orders"`
+	Courier  cm.Courier `json:"courier"`
+	Orders   []om.Order `json:"orders"`   // новые заказы вокруг курьера
+	Carrying []om.Order `json:"carrying"` // заказы, которые везет курьер
+	Shift    *sm.Shift  `json:"shift"`    // активная смена курьера, nil - курьер не работает
 }
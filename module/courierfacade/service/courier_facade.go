@@ -2,66 +2,332 @@ package service
 
 import (
 	"context"
+	"errors"
+	"github.com/GoGerman/geo-task/cache"
+	"github.com/GoGerman/geo-task/geo"
 	"github.com/GoGerman/geo-task/module/courier/models"
 	cservice "github.com/GoGerman/geo-task/module/courier/service"
 	cfm "github.com/GoGerman/geo-task/module/courierfacade/models"
+	lservice "github.com/GoGerman/geo-task/module/leaderboard/service"
+	ledgerservice "github.com/GoGerman/geo-task/module/ledger/service"
 	om "github.com/GoGerman/geo-task/module/order/models"
 	oservice "github.com/GoGerman/geo-task/module/order/service"
+	sm "github.com/GoGerman/geo-task/module/shift/models"
+	sservice "github.com/GoGerman/geo-task/module/shift/service"
+	"log"
 )
 
 const (
 	CourierVisibilityRadius = 2500 // 2500m
+	DefaultPickupDistance   = 50   // 50m
 )
 
 type CourierFacer interface {
-	MoveCourier(ctx context.Context, direction, zoom int) // отвечает за движение курьера по карте direction - направление движения, zoom - уровень зума
-	GetStatus(ctx context.Context) cfm.CourierStatus      // отвечает за получение статуса курьера и заказов вокруг него
+	MoveCourier(ctx context.Context, courierID string, direction, zoom int)               // отвечает за движение курьера courierID по карте direction - направление движения, zoom - уровень зума, вне смены шаг отклоняется
+	UpdatePosition(ctx context.Context, courierID string, position models.Position) error // отвечает за перенос курьера courierID в абсолютную точку с устройства, вне смены возвращает ErrOffShift
+	Exists(ctx context.Context, courierID string) (bool, error)                           // отвечает за проверку, что курьер courierID есть, не меняя его
+	Heartbeat(ctx context.Context, courierID string) error                                // отвечает за продление присутствия курьера courierID, пока открыт WebSocket
+	GetStatus(ctx context.Context, courierID string) (cfm.CourierStatus, error)           // отвечает за получение статуса курьера courierID и заказов вокруг него, вне смены заказов нет
 }
 
 // CourierFacade фасад для курьера и заказов вокруг него (для фронта)
 type CourierFacade struct {
-	courierService cservice.Courierer
-	orderService   oservice.Orderer
+	courierService     cservice.Courierer
+	orderService       oservice.Orderer
+	shiftService       sservice.Shifter
+	leaderboardService lservice.Leaderboarder
+	ledgerService      ledgerservice.Ledgerer
+	pickupDistance     float64
 }
 
-func NewCourierFacade(courierService cservice.Courierer, orderService oservice.Orderer) CourierFacer {
-	return &CourierFacade{courierService: courierService, orderService: orderService}
+// NewCourierFacade pickupDistance - расстояние в метрах, с которого курьер забирает заказ
+func NewCourierFacade(courierService cservice.Courierer, orderService oservice.Orderer, shiftService sservice.Shifter, leaderboardService lservice.Leaderboarder, ledgerService ledgerservice.Ledgerer, pickupDistance float64) CourierFacer {
+	return &CourierFacade{
+		courierService:     courierService,
+		orderService:       orderService,
+		shiftService:       shiftService,
+		leaderboardService: leaderboardService,
+		ledgerService:      ledgerService,
+		pickupDistance:     pickupDistance,
+	}
+}
+
+func (c *CourierFacade) Exists(ctx context.Context, courierID string) (bool, error) {
+	return c.courierService.Exists(ctx, courierID)
 }
 
-func (c *CourierFacade) MoveCourier(ctx context.Context, direction, zoom int) {
-	var courier *models.Courier
-	var err error
+func (c *CourierFacade) Heartbeat(ctx context.Context, courierID string) error {
+	return c.courierService.Heartbeat(ctx, courierID)
+}
 
-	courier, err = c.courierService.GetCourier(ctx)
+// onShift возвращает курьера с состоянием, приведенным к его смене, и активную смену курьера.
+// Курьеру, который довозит заказы после смены, возвращается его последняя смена вместе с sservice.ErrShiftFinished
+func (c *CourierFacade) onShift(ctx context.Context, courierID string) (*models.Courier, *sm.Shift, error) {
+	courier, err := c.courierService.GetCourier(ctx, courierID)
 	if err != nil {
+		return nil, nil, err
+	}
+
+	courier, shift, err := c.shiftService.Sync(ctx, *courier)
+	if errors.Is(err, sservice.ErrShiftFinished) {
+		return courier, shift, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if shift == nil {
+		return courier, nil, sservice.ErrOffShift
+	}
+
+	return courier, shift, nil
+}
+
+// addDistance добавляет в итоги смены расстояние между положениями курьера до и после перемещения
+func (c *CourierFacade) addDistance(ctx context.Context, shift sm.Shift, from, to models.Point) {
+	distance := geo.Distance(geo.Point{Lat: from.Lat, Lng: from.Lng}, geo.Point{Lat: to.Lat, Lng: to.Lng})
+	if distance == 0 {
+		return
+	}
+
+	err := c.shiftService.AddDistance(ctx, shift.ID, distance)
+	if err != nil {
+		log.Printf("error while adding distance to shift %s: %v", shift.ID, err)
+	}
+}
+
+func (c *CourierFacade) MoveCourier(ctx context.Context, courierID string, direction, zoom int) {
+	courier, shift, err := c.onShift(ctx, courierID)
+	finishing := errors.Is(err, sservice.ErrShiftFinished)
+	if err != nil && !finishing {
+		log.Printf("error while moving courier %s: %v", courierID, err)
 		return
 	}
 
-	c.courierService.MoveCourier(*courier, direction, zoom)
+	from := courier.Location
 
+	courier, err = c.courierService.MoveCourier(ctx, courierID, direction, zoom)
+	if err != nil {
+		log.Printf("error while moving courier %s: %v", courierID, err)
+		return
+	}
+
+	c.addDistance(ctx, *shift, from, courier.Location)
+	c.deliverOrders(ctx, *courier, *shift, !finishing)
 }
 
-func (c *CourierFacade) GetStatus(ctx context.Context) (res cfm.CourierStatus) {
-	var courier *models.Courier
-	var orders []om.Order
-	var err error
+func (c *CourierFacade) UpdatePosition(ctx context.Context, courierID string, position models.Position) error {
+	courier, shift, err := c.onShift(ctx, courierID)
+	finishing := errors.Is(err, sservice.ErrShiftFinished)
+	if err != nil && !finishing {
+		return err
+	}
 
-	courier, err = c.courierService.GetCourier(ctx)
+	from := courier.Location
 
+	courier, err = c.courierService.UpdatePosition(ctx, courierID, position)
 	if err != nil {
+		return err
+	}
+
+	c.addDistance(ctx, *shift, from, courier.Location)
+	c.deliverOrders(ctx, *courier, *shift, !finishing)
+
+	return nil
+}
+
+// deliverOrders доставляет заказы, точка доставки которых в радиусе pickupDistance от курьера,
+// и, если pickUp, забирает новые заказы рядом с курьером, пока позволяет вместимость транспорта.
+// Доставка идет первой, чтобы освободившееся место сразу можно было занять
+func (c *CourierFacade) deliverOrders(ctx context.Context, courier models.Courier, shift sm.Shift, pickUp bool) {
+	courier = c.dropOffOrders(ctx, courier, shift)
+	if pickUp {
+		c.pickUpOrders(ctx, courier, shift)
+	}
+}
+
+// dropOffOrders доставляет заказы курьера, до точки доставки которых не больше pickupDistance,
+// и возвращает курьера после доставки
+func (c *CourierFacade) dropOffOrders(ctx context.Context, courier models.Courier, shift sm.Shift) models.Courier {
+	location := geo.Point{Lat: courier.Location.Lat, Lng: courier.Location.Lng}
+
+	// список заказов меняется при доставке, поэтому проходим по копии
+	carried := append([]int64(nil), courier.Orders...)
+	for _, orderID := range carried {
+		order, err := c.orderService.GetByID(ctx, orderID)
+		if err != nil {
+			log.Printf("error while getting order %d: %v", orderID, err)
+			continue
+		}
+
+		// заказ истек в пути, курьер больше не может его доставить
+		if order == nil {
+			log.Printf("order %d carried by courier %s expired", orderID, courier.ID)
+			courier = c.dropOff(ctx, courier, orderID)
+			continue
+		}
+
+		lat, lng := order.Dropoff()
+		if geo.Distance(location, geo.Point{Lat: lat, Lng: lng}) > c.pickupDistance {
+			continue
+		}
+
+		// заказ, очки, итоги смены и оплата сохраняются одной транзакцией вместе с курьером:
+		// либо доставка засчитана целиком, либо не засчитана совсем и повторится при следующем обновлении
+		steps := []cache.TxStep{
+			c.orderService.DeliverOrder(orderID, courier.ID),
+			c.leaderboardService.AddScoreStep(courier.ID, 1),
+			c.shiftService.AddDeliveryStep(shift.ID, order.DeliveryPrice),
+		}
+
+		// бесплатная доставка не попадает в журнал начислений
+		if order.DeliveryPrice > 0 {
+			payout, err := c.ledgerService.PayoutStep(courier.ID, orderID, order.DeliveryPrice)
+			if err != nil {
+				log.Printf("error while recording payout for order %d to courier %s: %v", orderID, courier.ID, err)
+				continue
+			}
+			steps = append(steps, payout)
+		}
+
+		delivered, err := c.courierService.Deliver(ctx, courier.ID, orderID, steps...)
+		// заказ уже отмечен доставленным, курьеру остается только избавиться от него
+		if errors.Is(err, oservice.ErrOrderNotCarried) {
+			courier = c.dropOff(ctx, courier, orderID)
+			continue
+		}
+		if err != nil {
+			log.Printf("error while delivering order %d: %v", orderID, err)
+			continue
+		}
+
+		courier = *delivered
+	}
+
+	return courier
+}
+
+// dropOff убирает заказ из заказов курьера, при ошибке возвращает курьера без изменений
+func (c *CourierFacade) dropOff(ctx context.Context, courier models.Courier, orderID int64) models.Courier {
+	updated, err := c.courierService.DropOff(ctx, courier.ID, orderID)
+	if err != nil {
+		log.Printf("error while dropping off order %d by courier %s: %v", orderID, courier.ID, err)
+		return courier
+	}
+
+	return *updated
+}
+
+// pickUpOrders забирает заказы зоны смены в радиусе pickupDistance от курьера в пределах свободного места.
+// Заказ закрепляется за курьером в одной транзакции с проверкой вместимости и сохранением курьера,
+// поэтому два курьера не могут забрать один заказ, а заказ, который курьер не смог забрать, остается доступным
+func (c *CourierFacade) pickUpOrders(ctx context.Context, courier models.Courier, shift sm.Shift) {
+	if !canPickUp(courier) {
 		return
 	}
 
-	orders, err = c.orderService.GetByRadius(
-		ctx,
-		courier.Location.Lng,
-		courier.Location.Lat,
-		CourierVisibilityRadius,
-		"m",
-	)
+	orders, err := c.orderService.GetByRadius(ctx, courier.Location.Lng, courier.Location.Lat, c.pickupDistance, "m")
+	if err != nil {
+		log.Printf("error while getting orders for pickup: %v", err)
+		return
+	}
+	orders = c.inShiftZone(shift, orders)
 
-	return cfm.CourierStatus{
-		Courier: *courier,
-		Orders:  orders,
+	for i := range orders {
+		if courier.FreeCapacity() <= 0 {
+			return
+		}
+
+		updated, err := c.courierService.PickUp(ctx, courier.ID, orders[i].ID, c.orderService.ClaimOrder(orders[i].ID, courier.ID))
+
+		// заказ уже забрал другой курьер
+		if errors.Is(err, oservice.ErrOrderTaken) {
+			continue
+		}
+
+		// место заняли параллельным запросом
+		if errors.Is(err, cservice.ErrCapacityExceeded) {
+			return
+		}
+
+		if err != nil {
+			log.Printf("error while picking up order %d by courier %s: %v", orders[i].ID, courier.ID, err)
+			return
+		}
+
+		courier = *updated
+	}
+}
+
+// inShiftZone оставляет заказы, которые забирают в зоне смены
+func (c *CourierFacade) inShiftZone(shift sm.Shift, orders []om.Order) []om.Order {
+	res := orders[:0]
+	for i := range orders {
+		if c.shiftService.InZone(shift, models.Point{Lat: orders[i].Lat, Lng: orders[i].Lng}) {
+			res = append(res, orders[i])
+		}
 	}
+
+	return res
+}
+
+// canPickUp проверяет, может ли курьер забрать еще один заказ: он свободен или уже везет заказы и у него есть место
+func canPickUp(courier models.Courier) bool {
+	if courier.State != models.CourierIdle && courier.State != models.CourierDelivering {
+		return false
+	}
+
+	return courier.FreeCapacity() > 0
+}
+
+// carriedOrders возвращает заказы, которые везет курьер, истекшие заказы пропускаются
+func (c *CourierFacade) carriedOrders(ctx context.Context, courier models.Courier) []om.Order {
+	orders := make([]om.Order, 0, len(courier.Orders))
+	for _, orderID := range courier.Orders {
+		order, err := c.orderService.GetByID(ctx, orderID)
+		if err != nil {
+			log.Printf("error while getting order %d: %v", orderID, err)
+			continue
+		}
+
+		if order != nil {
+			orders = append(orders, *order)
+		}
+	}
+
+	return orders
+}
+
+func (c *CourierFacade) GetStatus(ctx context.Context, courierID string) (cfm.CourierStatus, error) {
+	var orders []om.Order
+
+	// курьер вне смены и курьер, который довозит заказы после смены, получают статус без новых заказов
+	courier, shift, err := c.onShift(ctx, courierID)
+	finishing := errors.Is(err, sservice.ErrShiftFinished)
+	if err != nil && !finishing && !errors.Is(err, sservice.ErrOffShift) {
+		return cfm.CourierStatus{}, err
+	}
+
+	// новые заказы видят только курьеры, которые могут их забрать, и только в зоне смены
+	if shift != nil && !finishing && canPickUp(*courier) {
+		orders, err = c.orderService.GetByRadius(
+			ctx,
+			courier.Location.Lng,
+			courier.Location.Lat,
+			CourierVisibilityRadius,
+			"m",
+		)
+
+		if err != nil {
+			return cfm.CourierStatus{}, err
+		}
+		orders = c.inShiftZone(*shift, orders)
+	}
+
+	return cfm.CourierStatus{
+		Courier:  *courier,
+		Orders:   orders,
+		Carrying: c.carriedOrders(ctx, *courier),
+		Shift:    shift,
+	}, nil
 }
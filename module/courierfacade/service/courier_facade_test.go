@@ -0,0 +1,467 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/GoGerman/geo-task/cache"
+	"github.com/GoGerman/geo-task/clock"
+	"github.com/GoGerman/geo-task/geo"
+	"github.com/GoGerman/geo-task/internal/testutil"
+	"github.com/GoGerman/geo-task/module/courier/models"
+	cservice "github.com/GoGerman/geo-task/module/courier/service"
+	lm "github.com/GoGerman/geo-task/module/leaderboard/models"
+	lservice "github.com/GoGerman/geo-task/module/leaderboard/service"
+	lstorage "github.com/GoGerman/geo-task/module/leaderboard/storage"
+	ledgerservice "github.com/GoGerman/geo-task/module/ledger/service"
+	ledgerstorage "github.com/GoGerman/geo-task/module/ledger/storage"
+	om "github.com/GoGerman/geo-task/module/order/models"
+	oservice "github.com/GoGerman/geo-task/module/order/service"
+	ostorage "github.com/GoGerman/geo-task/module/order/storage"
+	sm "github.com/GoGerman/geo-task/module/shift/models"
+	sservice "github.com/GoGerman/geo-task/module/shift/service"
+	sstorage "github.com/GoGerman/geo-task/module/shift/storage"
+	"github.com/GoGerman/geo-task/random"
+	"github.com/redis/go-redis/v9"
+	"sync"
+	"testing"
+	"time"
+)
+
+// nearbyOrders возвращает заказы рядом с курьером из заданного списка, остальные методы - настоящего сервиса заказов
+type nearbyOrders struct {
+	oservice.Orderer
+	ids []int64
+}
+
+func (n nearbyOrders) GetByRadius(ctx context.Context, lng, lat, radius float64, unit string) ([]om.Order, error) {
+	orders := make([]om.Order, 0, len(n.ids))
+	for _, id := range n.ids {
+		order, err := n.Orderer.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if order != nil && !order.IsPickedUp {
+			orders = append(orders, *order)
+		}
+	}
+
+	return orders, nil
+}
+
+// failingPickUp сервис курьеров, который не может сохранить курьера с заказом
+type failingPickUp struct {
+	cservice.Courierer
+}
+
+func (failingPickUp) PickUp(ctx context.Context, id string, orderID int64, steps ...cache.TxStep) (*models.Courier, error) {
+	return nil, errors.New("redis is unavailable")
+}
+
+// anyZone смены, зона которых охватывает весь город
+type anyZone struct {
+	sservice.Shifter
+}
+
+func (anyZone) InZone(shift sm.Shift, point models.Point) bool {
+	return true
+}
+
+// otherZone смены, в зону которых не попадает ни один заказ
+type otherZone struct {
+	sservice.Shifter
+}
+
+func (otherZone) InZone(shift sm.Shift, point models.Point) bool {
+	return false
+}
+
+// activeShift смена, которая идет у любого курьера, пройденное за смену расстояние запоминается
+type activeShift struct {
+	sservice.Shifter
+	shift    sm.Shift
+	distance *float64
+}
+
+func (s activeShift) Sync(ctx context.Context, courier models.Courier) (*models.Courier, *sm.Shift, error) {
+	return &courier, &s.shift, nil
+}
+
+func (s activeShift) AddDistance(ctx context.Context, id string, distance float64) error {
+	*s.distance += distance
+	return nil
+}
+
+func (activeShift) InZone(shift sm.Shift, point models.Point) bool {
+	return true
+}
+
+type testEnv struct {
+	client   *redis.Client
+	couriers cservice.Courierer
+	orders   oservice.Orderer
+	clock    *clock.Manual
+}
+
+func newTestEnv(t *testing.T) testEnv {
+	t.Helper()
+
+	env := testutil.NewEnv(t)
+	orderStorage := ostorage.NewOrderStorage(env.Client, env.Clock)
+	orders := oservice.NewOrderService(orderStorage, env.Zones, env.Clock, random.New(2), orderStorage)
+
+	return testEnv{client: env.Client, couriers: env.Couriers(testutil.CourierOptions{}), orders: orders, clock: env.Clock}
+}
+
+// idleCourier создает курьера на линии
+func (e testEnv) idleCourier(t *testing.T, vehicle models.Vehicle) models.Courier {
+	t.Helper()
+
+	return *testutil.IdleCourier(t, e.couriers, vehicle)
+}
+
+// placeOrders создает заказы в точке курьера
+func (e testEnv) placeOrders(t *testing.T, courier models.Courier, ids ...int64) {
+	t.Helper()
+
+	for _, id := range ids {
+		err := e.orders.Save(context.Background(), om.Order{
+			ID:        id,
+			Lng:       courier.Location.Lng,
+			Lat:       courier.Location.Lat,
+			CreatedAt: e.clock.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func (e testEnv) facade(couriers cservice.Courierer, ids ...int64) *CourierFacade {
+	return &CourierFacade{
+		courierService: couriers,
+		orderService:   nearbyOrders{Orderer: e.orders, ids: ids},
+		shiftService:   anyZone{},
+		pickupDistance: DefaultPickupDistance,
+	}
+}
+
+// assertAvailable проверяет, что заказы не забраны и остались в индексе доступных заказов
+func (e testEnv) assertAvailable(t *testing.T, want int, ids ...int64) {
+	t.Helper()
+
+	ctx := context.Background()
+	for _, id := range ids {
+		order, err := e.orders.GetByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if order == nil || order.IsPickedUp {
+			t.Errorf("order %d = %+v, want available", id, order)
+		}
+	}
+
+	count, err := e.orders.GetCount(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != want {
+		t.Errorf("GetCount() = %d, want %d", count, want)
+	}
+}
+
+func TestPickUpOrdersKeepsOrderWhenPickUpFails(t *testing.T) {
+	env := newTestEnv(t)
+	courier := env.idleCourier(t, models.VehicleFoot)
+	env.placeOrders(t, courier, 1)
+
+	env.facade(failingPickUp{Courierer: env.couriers}, 1).pickUpOrders(context.Background(), courier, sm.Shift{})
+
+	env.assertAvailable(t, 1, 1)
+}
+
+func TestPickUpOrdersKeepsOrderWhenCourierIsFull(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	courier := env.idleCourier(t, models.VehicleFoot)
+
+	// пока фасад держит снимок свободного курьера, курьер параллельно забрал заказы до заполнения
+	for _, id := range []int64{100, 101} {
+		_, err := env.couriers.PickUp(ctx, courier.ID, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	env.placeOrders(t, courier, 1)
+	env.facade(env.couriers, 1).pickUpOrders(ctx, courier, sm.Shift{})
+
+	env.assertAvailable(t, 1, 1)
+}
+
+func TestConcurrentPickUpsNeverExceedCapacity(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	courier := env.idleCourier(t, models.VehicleFoot)
+
+	ids := []int64{1, 2, 3, 4, 5}
+	env.placeOrders(t, courier, ids...)
+
+	// несколько обновлений положения одного курьера обрабатываются параллельно со снимком без заказов
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			env.facade(env.couriers, ids...).pickUpOrders(ctx, courier, sm.Shift{})
+		}()
+	}
+	wg.Wait()
+
+	got, err := env.couriers.GetCourier(ctx, courier.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	capacity := models.VehicleFoot.Capacity()
+	if len(got.Orders) != capacity {
+		t.Fatalf("courier carries %v, want %d orders", got.Orders, capacity)
+	}
+
+	picked := 0
+	for _, id := range ids {
+		order, err := env.orders.GetByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if order.IsPickedUp {
+			picked++
+			if order.CourierID != courier.ID || !got.Carries(id) {
+				t.Errorf("order %d picked up by %q, courier carries %v", id, order.CourierID, got.Orders)
+			}
+		}
+	}
+
+	if picked != capacity {
+		t.Errorf("picked up %d orders, want %d", picked, capacity)
+	}
+
+	count, err := env.orders.GetCount(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(ids)-capacity {
+		t.Errorf("GetCount() = %d, want %d", count, len(ids)-capacity)
+	}
+}
+
+func TestPickUpOrdersOutsideShiftZone(t *testing.T) {
+	env := newTestEnv(t)
+	courier := env.idleCourier(t, models.VehicleFoot)
+	env.placeOrders(t, courier, 1)
+
+	facade := env.facade(env.couriers, 1)
+	facade.shiftService = otherZone{}
+	facade.pickUpOrders(context.Background(), courier, sm.Shift{ZoneID: "district"})
+
+	env.assertAvailable(t, 1, 1)
+}
+
+func TestConcurrentCouriersClaimOrderOnce(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	couriers := []models.Courier{env.idleCourier(t, models.VehicleCar), env.idleCourier(t, models.VehicleCar)}
+	env.placeOrders(t, couriers[0], 1, 2, 3)
+
+	var wg sync.WaitGroup
+	for i := range couriers {
+		wg.Add(1)
+		go func(courier models.Courier) {
+			defer wg.Done()
+			env.facade(env.couriers, 1, 2, 3).pickUpOrders(ctx, courier, sm.Shift{})
+		}(couriers[i])
+	}
+	wg.Wait()
+
+	carried := 0
+	for i := range couriers {
+		got, err := env.couriers.GetCourier(ctx, couriers[i].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		carried += len(got.Orders)
+
+		for _, id := range got.Orders {
+			order, err := env.orders.GetByID(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if order.CourierID != got.ID {
+				t.Errorf("courier %s carries order %d picked up by %q", got.ID, id, order.CourierID)
+			}
+		}
+	}
+
+	if carried != 3 {
+		t.Errorf("couriers carry %d orders, want 3", carried)
+	}
+}
+
+func TestDropOffOrdersCreditsDeliveryOnce(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	courier := env.idleCourier(t, models.VehicleFoot)
+
+	// цена с 17 значащими цифрами не должна округлиться при отметке о доставке
+	const price = 123.45678901234567
+	err := env.orders.Save(ctx, om.Order{
+		ID:            1,
+		DeliveryPrice: price,
+		Lng:           courier.Location.Lng,
+		Lat:           courier.Location.Lat,
+		CreatedAt:     env.clock.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaderboard := lservice.NewLeaderboardService(lstorage.NewLeaderboardStorage(env.client), env.couriers, env.clock, time.UTC)
+	ledger := ledgerservice.NewLedgerService(ledgerstorage.NewLedgerStorage(env.client), env.couriers, env.clock)
+	shifts := sservice.NewShiftService(sstorage.NewShiftStorage(env.client), env.couriers, nil, env.clock)
+
+	facade := env.facade(env.couriers, 1)
+	facade.shiftService = anyZone{Shifter: shifts}
+	facade.leaderboardService = leaderboard
+	facade.ledgerService = ledger
+
+	shift := sm.Shift{ID: "1", CourierID: courier.ID}
+	facade.pickUpOrders(ctx, courier, shift)
+
+	carrying, err := env.couriers.GetCourier(ctx, courier.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// второй вызов со старым снимком курьера, который еще везет заказ, не должен засчитать доставку снова
+	for i := 0; i < 2; i++ {
+		facade.dropOffOrders(ctx, *carrying, shift)
+	}
+
+	got, err := env.couriers.GetCourier(ctx, courier.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Orders) != 0 || got.Score != 1 {
+		t.Errorf("courier orders = %v, score = %d, want no orders and score 1", got.Orders, got.Score)
+	}
+
+	order, err := env.orders.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !order.IsDelivered || order.DeliveryPrice != price {
+		t.Errorf("order delivered = %v, delivery price = %v, want delivered with %v", order.IsDelivered, order.DeliveryPrice, price)
+	}
+
+	board, err := leaderboard.Leaderboard(ctx, lm.PeriodAll, 0, courier.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if board.Courier == nil || board.Courier.Score != 1 {
+		t.Errorf("leaderboard entry = %+v, want score 1", board.Courier)
+	}
+
+	deliveries, err := env.client.HGet(ctx, sstorage.ShiftKey(shift.ID), "deliveries").Int()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deliveries != 1 {
+		t.Errorf("shift deliveries = %d, want 1", deliveries)
+	}
+
+	balance, err := ledger.Balance(ctx, courier.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Balance != 12346 {
+		t.Errorf("Balance() = %d, want 12346", balance.Balance)
+	}
+}
+
+func TestUpdatePositionMovesCourierOnShift(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	courier := env.idleCourier(t, models.VehicleFoot)
+	start := env.clock.Now()
+
+	var distance float64
+	facade := env.facade(env.couriers)
+	facade.shiftService = activeShift{shift: sm.Shift{ID: "1", CourierID: courier.ID}, distance: &distance}
+
+	// около 100 м за минуту по времени устройства
+	target := models.Point{Lat: courier.Location.Lat + 0.0009, Lng: courier.Location.Lng}
+	env.clock.Add(time.Minute)
+
+	err := facade.UpdatePosition(ctx, courier.ID, models.Position{
+		Location:  target,
+		Accuracy:  5,
+		Heading:   90,
+		Speed:     1.5,
+		Timestamp: start.Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("UpdatePosition() error = %v", err)
+	}
+
+	got, err := env.couriers.GetCourier(ctx, courier.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Location != target {
+		t.Errorf("Location = %+v, want %+v", got.Location, target)
+	}
+
+	want := geo.Distance(geo.Point{Lat: courier.Location.Lat, Lng: courier.Location.Lng}, geo.Point{Lat: target.Lat, Lng: target.Lng})
+	if distance != want {
+		t.Errorf("shift distance = %v, want %v", distance, want)
+	}
+
+	// данные устройства сохраняются в треке вместе с точкой
+	track, err := env.couriers.Track(ctx, courier.ID, start, env.clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(track) == 0 {
+		t.Fatal("track is empty")
+	}
+	last := track[len(track)-1]
+	if last.Location != target || last.Accuracy != 5 || last.Heading != 90 || last.Speed != 1.5 {
+		t.Errorf("last track point = %+v, want device position at %+v", last, target)
+	}
+}
+
+func TestUpdatePositionOffShift(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	courier := env.idleCourier(t, models.VehicleFoot)
+
+	facade := env.facade(env.couriers)
+	facade.shiftService = sservice.NewShiftService(sstorage.NewShiftStorage(env.client), env.couriers, nil, env.clock)
+
+	target := models.Point{Lat: courier.Location.Lat + 0.0009, Lng: courier.Location.Lng}
+	env.clock.Add(time.Minute)
+
+	err := facade.UpdatePosition(ctx, courier.ID, models.Position{Location: target})
+	if !errors.Is(err, sservice.ErrOffShift) {
+		t.Fatalf("UpdatePosition() error = %v, want %v", err, sservice.ErrOffShift)
+	}
+
+	got, err := env.couriers.GetCourier(ctx, courier.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Location != courier.Location {
+		t.Errorf("Location = %+v, want unchanged %+v", got.Location, courier.Location)
+	}
+}
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"errors"
+	"github.com/GoGerman/geo-task/module/leaderboard/models"
+	"github.com/GoGerman/geo-task/module/leaderboard/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type LeaderboardController struct {
+	leaderboardService service.Leaderboarder
+}
+
+func NewLeaderboardController(leaderboardService service.Leaderboarder) *LeaderboardController {
+	return &LeaderboardController{leaderboardService: leaderboardService}
+}
+
+// Get отдает лучших курьеров за период day, week или all и место курьера courier_id
+func (l *LeaderboardController) Get(ctx *gin.Context) {
+	period := models.Period(ctx.DefaultQuery("period", string(models.PeriodAll)))
+
+	limit := service.DefaultLimit
+	if v := ctx.Query("limit"); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit " + v})
+			return
+		}
+	}
+
+	board, err := l.leaderboardService.Leaderboard(ctx, period, limit, ctx.Query("courier_id"))
+	if errors.Is(err, service.ErrUnknownPeriod) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, board)
+}
@@ -0,0 +1,34 @@
+package models
+
+// Period период таблицы лидеров
+type Period string
+
+const (
+	PeriodDay  Period = "day"  // текущие сутки
+	PeriodWeek Period = "week" // текущая неделя с понедельника
+	PeriodAll  Period = "all"  // за все время
+)
+
+// Valid проверяет, что период известен
+func (p Period) Valid() bool {
+	switch p {
+	case PeriodDay, PeriodWeek, PeriodAll:
+		return true
+	}
+
+	return false
+}
+
+// Entry место курьера в таблице лидеров
+type Entry struct {
+	Rank      int64  `json:"rank"` // место, начиная с 1
+	CourierID string `json:"courier_id"`
+	Score     int64  `json:"score"`
+}
+
+// Leaderboard таблица лидеров за период
+type Leaderboard struct {
+	Period  Period  `json:"period"`
+	Entries []Entry `json:"entries"`
+	Courier *Entry  `json:"courier"` // место запросившего курьера, nil если у него нет очков за период
+}
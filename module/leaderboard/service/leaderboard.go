@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/GoGerman/geo-task/cache"
+	"github.com/GoGerman/geo-task/clock"
+	cservice "github.com/GoGerman/geo-task/module/courier/service"
+	"github.com/GoGerman/geo-task/module/leaderboard/models"
+	"github.com/GoGerman/geo-task/module/leaderboard/storage"
+	"time"
+)
+
+const (
+	DefaultLimit = 10  // количество курьеров в таблице по умолчанию
+	MaxLimit     = 100 // максимальное количество курьеров в таблице
+)
+
+// boardRetention сколько таблица за период хранится после окончания периода
+const boardRetention = 24 * time.Hour
+
+var ErrUnknownPeriod = errors.New("unknown leaderboard period")
+
+type Leaderboarder interface {
+	AddScore(ctx context.Context, courierID string, delta int) error                                                 // начисляет курьеру очки в таблицах за сутки, неделю и все время
+	AddScoreStep(courierID string, delta int) cache.TxStep                                                           // возвращает шаг транзакции, начисляющий очки как AddScore, чтобы очки сохранились вместе с доставкой
+	Leaderboard(ctx context.Context, period models.Period, limit int, courierID string) (*models.Leaderboard, error) // возвращает limit лучших курьеров за период и место курьера courierID
+	Init(ctx context.Context) error                                                                                  // переносит очки существующих курьеров в таблицу за все время
+}
+
+// LeaderboardService таблицы лидеров в redis sorted set.
+// Таблицы за сутки и неделю ведутся под ключом своего периода, поэтому с началом нового периода
+// очки начинают копиться в новой таблице, а старая удаляется по времени жизни
+type LeaderboardService struct {
+	storage        storage.LeaderboardStorager
+	courierService cservice.Courierer
+	clock          clock.Clock
+	location       *time.Location
+}
+
+// NewLeaderboardService location - часовой пояс, в котором начинаются сутки и неделя
+func NewLeaderboardService(storage storage.LeaderboardStorager, courierService cservice.Courierer, clock clock.Clock, location *time.Location) Leaderboarder {
+	return &LeaderboardService{
+		storage:        storage,
+		courierService: courierService,
+		clock:          clock,
+		location:       location,
+	}
+}
+
+// BoardKey возвращает ключ таблицы за период, в который попадает момент t
+func BoardKey(period models.Period, t time.Time) string {
+	switch period {
+	case models.PeriodDay:
+		return "leaderboard:day:" + t.Format("2006-01-02")
+	case models.PeriodWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("leaderboard:week:%d-W%02d", year, week)
+	}
+
+	return "leaderboard:all"
+}
+
+// periodEnd возвращает момент окончания периода, в который попадает t, нулевое время для таблицы за все время
+func periodEnd(period models.Period, t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+	switch period {
+	case models.PeriodDay:
+		return day.AddDate(0, 0, 1)
+	case models.PeriodWeek:
+		// неделя начинается с понедельника, как в ISOWeek
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, 7-daysSinceMonday)
+	}
+
+	return time.Time{}
+}
+
+func (l *LeaderboardService) AddScore(ctx context.Context, courierID string, delta int) error {
+	return l.storage.Incr(ctx, l.boards(), courierID, delta)
+}
+
+func (l *LeaderboardService) AddScoreStep(courierID string, delta int) cache.TxStep {
+	return l.storage.IncrStep(l.boards(), courierID, delta)
+}
+
+// boards возвращает таблицы за текущие сутки, неделю и все время
+func (l *LeaderboardService) boards() []storage.Board {
+	now := l.clock.Now().In(l.location)
+
+	periods := []models.Period{models.PeriodDay, models.PeriodWeek, models.PeriodAll}
+	boards := make([]storage.Board, 0, len(periods))
+	for _, period := range periods {
+		board := storage.Board{Key: BoardKey(period, now)}
+		if end := periodEnd(period, now); !end.IsZero() {
+			board.ExpireAt = end.Add(boardRetention)
+		}
+		boards = append(boards, board)
+	}
+
+	return boards
+}
+
+func (l *LeaderboardService) Leaderboard(ctx context.Context, period models.Period, limit int, courierID string) (*models.Leaderboard, error) {
+	if !period.Valid() {
+		return nil, fmt.Errorf("%w %q", ErrUnknownPeriod, period)
+	}
+
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	key := BoardKey(period, l.clock.Now().In(l.location))
+
+	entries, err := l.storage.Top(ctx, key, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	board := &models.Leaderboard{
+		Period:  period,
+		Entries: entries,
+	}
+
+	if courierID != "" {
+		board.Courier, err = l.storage.Rank(ctx, key, courierID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return board, nil
+}
+
+// Init переносит очки курьеров, набранные до появления таблиц лидеров.
+// Курьеры, которые уже есть в таблице за все время, не меняются, поэтому повторный запуск безопасен
+func (l *LeaderboardService) Init(ctx context.Context) error {
+	couriers, err := l.courierService.ListCouriers(ctx)
+	if err != nil {
+		return err
+	}
+
+	scores := make(map[string]int, len(couriers))
+	for i := range couriers {
+		if couriers[i].Score > 0 {
+			scores[couriers[i].ID] = couriers[i].Score
+		}
+	}
+
+	return l.storage.Init(ctx, BoardKey(models.PeriodAll, time.Time{}), scores)
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"github.com/GoGerman/geo-task/internal/testutil"
+	cmodels "github.com/GoGerman/geo-task/module/courier/models"
+	cservice "github.com/GoGerman/geo-task/module/courier/service"
+	"github.com/GoGerman/geo-task/module/leaderboard/models"
+	"github.com/GoGerman/geo-task/module/leaderboard/storage"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// moscow сутки и неделя начинаются на 3 часа раньше, чем в UTC. testutil.Start - понедельник, 15:00 по Москве
+var moscow = time.FixedZone("MSK", 3*60*60)
+
+func newTestLeaderboard(t *testing.T) (Leaderboarder, cservice.Courierer, *testutil.Env) {
+	t.Helper()
+
+	env := testutil.NewEnv(t)
+	couriers := env.Couriers(testutil.CourierOptions{})
+
+	return NewLeaderboardService(storage.NewLeaderboardStorage(env.Client), couriers, env.Clock, moscow), couriers, env
+}
+
+func addScore(t *testing.T, board Leaderboarder, courierID string, delta int) {
+	t.Helper()
+
+	err := board.AddScore(context.Background(), courierID, delta)
+	if err != nil {
+		t.Fatalf("AddScore() error = %v", err)
+	}
+}
+
+func getLeaderboard(t *testing.T, board Leaderboarder, period models.Period, limit int, courierID string) *models.Leaderboard {
+	t.Helper()
+
+	res, err := board.Leaderboard(context.Background(), period, limit, courierID)
+	if err != nil {
+		t.Fatalf("Leaderboard(%s) error = %v", period, err)
+	}
+
+	return res
+}
+
+// scores возвращает очки курьеров в таблице за период
+func scores(t *testing.T, board Leaderboarder, period models.Period) map[string]int64 {
+	t.Helper()
+
+	res := make(map[string]int64)
+	for _, entry := range getLeaderboard(t, board, period, MaxLimit, "").Entries {
+		res[entry.CourierID] = entry.Score
+	}
+
+	return res
+}
+
+func TestLeaderboardRollsOverDayAndWeek(t *testing.T) {
+	board, _, env := newTestLeaderboard(t)
+
+	addScore(t, board, "1", 2)
+
+	// 23:59 понедельника по Москве, в UTC уже другие часы, но сутки те же
+	env.Advance(9*time.Hour - time.Minute)
+	addScore(t, board, "1", 1)
+
+	want := map[models.Period]int64{models.PeriodDay: 3, models.PeriodWeek: 3, models.PeriodAll: 3}
+	for period, score := range want {
+		if got := scores(t, board, period)["1"]; got != score {
+			t.Errorf("monday: %s score = %d, want %d", period, got, score)
+		}
+	}
+
+	// полночь вторника по Москве, в UTC еще понедельник
+	env.Advance(time.Minute)
+	addScore(t, board, "1", 1)
+
+	want = map[models.Period]int64{models.PeriodDay: 1, models.PeriodWeek: 4, models.PeriodAll: 4}
+	for period, score := range want {
+		if got := scores(t, board, period)["1"]; got != score {
+			t.Errorf("tuesday: %s score = %d, want %d", period, got, score)
+		}
+	}
+
+	// таблица прошлых суток хранится еще сутки после их окончания
+	if ttl := env.Redis.TTL("leaderboard:day:2024-01-01"); ttl != 24*time.Hour {
+		t.Errorf("monday board TTL = %v, want %v", ttl, 24*time.Hour)
+	}
+
+	// полночь следующего понедельника по Москве
+	env.Advance(6 * 24 * time.Hour)
+	if got := scores(t, board, models.PeriodWeek); len(got) != 0 {
+		t.Errorf("next week: week board = %v, want empty", got)
+	}
+
+	addScore(t, board, "1", 1)
+
+	want = map[models.Period]int64{models.PeriodDay: 1, models.PeriodWeek: 1, models.PeriodAll: 5}
+	for period, score := range want {
+		if got := scores(t, board, period)["1"]; got != score {
+			t.Errorf("next week: %s score = %d, want %d", period, got, score)
+		}
+	}
+
+	if env.Redis.Exists("leaderboard:day:2024-01-01") {
+		t.Error("monday board is not deleted a day after it ended")
+	}
+	if ttl := env.Redis.TTL("leaderboard:week:2024-W01"); ttl != 24*time.Hour {
+		t.Errorf("previous week board TTL = %v, want %v", ttl, 24*time.Hour)
+	}
+	if ttl := env.Redis.TTL("leaderboard:all"); ttl != 0 {
+		t.Errorf("all time board TTL = %v, want none", ttl)
+	}
+}
+
+func TestLeaderboardCourierRank(t *testing.T) {
+	board, _, _ := newTestLeaderboard(t)
+
+	addScore(t, board, "1", 5)
+	addScore(t, board, "2", 3)
+	addScore(t, board, "3", 1)
+
+	res := getLeaderboard(t, board, models.PeriodDay, 2, "3")
+
+	wantEntries := []models.Entry{
+		{Rank: 1, CourierID: "1", Score: 5},
+		{Rank: 2, CourierID: "2", Score: 3},
+	}
+	if !reflect.DeepEqual(res.Entries, wantEntries) {
+		t.Errorf("Entries = %+v, want %+v", res.Entries, wantEntries)
+	}
+
+	// курьер вне первых limit мест получает свое место отдельно
+	wantCourier := &models.Entry{Rank: 3, CourierID: "3", Score: 1}
+	if !reflect.DeepEqual(res.Courier, wantCourier) {
+		t.Errorf("Courier = %+v, want %+v", res.Courier, wantCourier)
+	}
+
+	res = getLeaderboard(t, board, models.PeriodDay, 2, "4")
+	if res.Courier != nil {
+		t.Errorf("Courier without score = %+v, want nil", res.Courier)
+	}
+
+	res = getLeaderboard(t, board, models.PeriodDay, 2, "")
+	if res.Courier != nil {
+		t.Errorf("Courier without courier_id = %+v, want nil", res.Courier)
+	}
+}
+
+func TestLeaderboardTies(t *testing.T) {
+	board, _, _ := newTestLeaderboard(t)
+
+	addScore(t, board, "1", 2)
+	addScore(t, board, "2", 2)
+	addScore(t, board, "3", 5)
+	addScore(t, board, "4", 2)
+
+	entries := getLeaderboard(t, board, models.PeriodWeek, MaxLimit, "").Entries
+	if len(entries) != 4 {
+		t.Fatalf("Entries = %+v, want 4 entries", entries)
+	}
+	if entries[0].CourierID != "3" {
+		t.Errorf("first courier = %s, want 3", entries[0].CourierID)
+	}
+
+	// курьеры с равными очками занимают соседние места в одном и том же порядке,
+	// и место курьера совпадает с его местом в таблице
+	for i, entry := range entries {
+		if entry.Rank != int64(i+1) {
+			t.Errorf("entry %d rank = %d, want %d", i, entry.Rank, i+1)
+		}
+
+		courier := getLeaderboard(t, board, models.PeriodWeek, 1, entry.CourierID).Courier
+		if courier == nil || *courier != entry {
+			t.Errorf("Courier(%s) = %+v, want %+v", entry.CourierID, courier, entry)
+		}
+	}
+
+	again := getLeaderboard(t, board, models.PeriodWeek, MaxLimit, "").Entries
+	if !reflect.DeepEqual(again, entries) {
+		t.Errorf("tied order changed: %+v, then %+v", entries, again)
+	}
+}
+
+func TestInitBackfillsAllTimeBoard(t *testing.T) {
+	ctx := context.Background()
+	board, couriers, _ := newTestLeaderboard(t)
+
+	scored := testutil.IdleCourier(t, couriers, cmodels.VehicleFoot)
+	unscored := testutil.IdleCourier(t, couriers, cmodels.VehicleFoot)
+	tracked := testutil.IdleCourier(t, couriers, cmodels.VehicleFoot)
+
+	for _, courier := range []*cmodels.Courier{scored, tracked} {
+		_, err := couriers.AddScore(ctx, courier.ID, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// у курьера уже есть очки в таблице, они набраны после ее появления и не перезаписываются
+	addScore(t, board, tracked.ID, 10)
+
+	for i := 0; i < 2; i++ {
+		err := board.Init(ctx)
+		if err != nil {
+			t.Fatalf("Init() error = %v", err)
+		}
+
+		want := map[string]int64{scored.ID: 3, tracked.ID: 10}
+		if got := scores(t, board, models.PeriodAll); !reflect.DeepEqual(got, want) {
+			t.Errorf("Init() #%d: all time scores = %v, want %v", i+1, got, want)
+		}
+	}
+
+	// старые очки не относятся к текущим суткам и неделе
+	for _, period := range []models.Period{models.PeriodDay, models.PeriodWeek} {
+		want := map[string]int64{tracked.ID: 10}
+		if got := scores(t, board, period); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s scores = %v, want %v", period, got, want)
+		}
+	}
+
+	if got := getLeaderboard(t, board, models.PeriodAll, MaxLimit, unscored.ID).Courier; got != nil {
+		t.Errorf("courier without score = %+v, want nil", got)
+	}
+}
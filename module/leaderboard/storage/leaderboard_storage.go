@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"github.com/GoGerman/geo-task/cache"
+	"github.com/GoGerman/geo-task/module/leaderboard/models"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// Board ключ таблицы лидеров и момент, когда она удаляется, нулевое время - таблица хранится всегда
+type Board struct {
+	Key      string
+	ExpireAt time.Time
+}
+
+type LeaderboardStorager interface {
+	Incr(ctx context.Context, boards []Board, courierID string, delta int) error // начислить курьеру очки во всех таблицах одной транзакцией
+	IncrStep(boards []Board, courierID string, delta int) cache.TxStep           // шаг транзакции, начисляющий курьеру очки во всех таблицах
+	Top(ctx context.Context, key string, n int) ([]models.Entry, error)          // получить n лучших курьеров таблицы
+	Rank(ctx context.Context, key, courierID string) (*models.Entry, error)      // получить место курьера в таблице, nil если курьера в ней нет
+	Init(ctx context.Context, key string, scores map[string]int) error           // добавить очки курьеров, которых еще нет в таблице
+}
+
+type LeaderboardStorage struct {
+	storage *redis.Client
+}
+
+func NewLeaderboardStorage(storage *redis.Client) LeaderboardStorager {
+	return &LeaderboardStorage{storage: storage}
+}
+
+func (s *LeaderboardStorage) Incr(ctx context.Context, boards []Board, courierID string, delta int) error {
+	step := s.IncrStep(boards, courierID, delta)
+
+	_, err := s.storage.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		step.Queue(ctx, pipe)
+		return nil
+	})
+
+	return err
+}
+
+func (s *LeaderboardStorage) IncrStep(boards []Board, courierID string, delta int) cache.TxStep {
+	return cache.QueueStep(func(ctx context.Context, pipe redis.Pipeliner) {
+		for i := range boards {
+			pipe.ZIncrBy(ctx, boards[i].Key, float64(delta), courierID)
+			// таблица за период удаляется сама после окончания периода
+			if !boards[i].ExpireAt.IsZero() {
+				pipe.ExpireAt(ctx, boards[i].Key, boards[i].ExpireAt)
+			}
+		}
+	})
+}
+
+func (s *LeaderboardStorage) Top(ctx context.Context, key string, n int) ([]models.Entry, error) {
+	scores, err := s.storage.ZRevRangeWithScores(ctx, key, 0, int64(n-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.Entry, 0, len(scores))
+	for i := range scores {
+		courierID, _ := scores[i].Member.(string)
+		entries = append(entries, models.Entry{
+			Rank:      int64(i + 1),
+			CourierID: courierID,
+			Score:     int64(scores[i].Score),
+		})
+	}
+
+	return entries, nil
+}
+
+func (s *LeaderboardStorage) Rank(ctx context.Context, key, courierID string) (*models.Entry, error) {
+	var rank *redis.IntCmd
+	var score *redis.FloatCmd
+
+	_, err := s.storage.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		rank = pipe.ZRevRank(ctx, key, courierID)
+		score = pipe.ZScore(ctx, key, courierID)
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Entry{
+		Rank:      rank.Val() + 1,
+		CourierID: courierID,
+		Score:     int64(score.Val()),
+	}, nil
+}
+
+func (s *LeaderboardStorage) Init(ctx context.Context, key string, scores map[string]int) error {
+	if len(scores) == 0 {
+		return nil
+	}
+
+	members := make([]redis.Z, 0, len(scores))
+	for courierID, score := range scores {
+		members = append(members, redis.Z{Score: float64(score), Member: courierID})
+	}
+
+	return s.storage.ZAddNX(ctx, key, members...).Err()
+}
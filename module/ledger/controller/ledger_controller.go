@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"errors"
+	cservice "github.com/GoGerman/geo-task/module/courier/service"
+	"github.com/GoGerman/geo-task/module/ledger/models"
+	"github.com/GoGerman/geo-task/module/ledger/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// EntryRequest запрос на ручную запись в журнал: бонус, штраф или корректировку
+type EntryRequest struct {
+	Type    models.EntryType `json:"type" binding:"required"`
+	Amount  int64            `json:"amount"` // сумма в копейках, для штрафа отрицательная
+	Reason  string           `json:"reason" binding:"required"`
+	OrderID int64            `json:"order_id"`
+}
+
+type LedgerController struct {
+	ledgerService service.Ledgerer
+}
+
+func NewLedgerController(ledgerService service.Ledgerer) *LedgerController {
+	return &LedgerController{ledgerService: ledgerService}
+}
+
+// List отдает страницу журнала курьера от новых записей к старым, before - курсор next с предыдущей страницы
+func (l *LedgerController) List(ctx *gin.Context) {
+	limit := service.DefaultLimit
+	if v := ctx.Query("limit"); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit " + v})
+			return
+		}
+	}
+
+	page, err := l.ledgerService.Entries(ctx, ctx.Param("id"), ctx.Query("before"), limit)
+	if err != nil {
+		l.error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, page)
+}
+
+func (l *LedgerController) Create(ctx *gin.Context) {
+	var req EntryRequest
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// оплата доставки начисляется только при доставке заказа
+	if req.Type == models.EntryPayout {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "payout entries are recorded on delivery"})
+		return
+	}
+
+	entry, err := l.ledgerService.Record(ctx, models.Entry{
+		CourierID: ctx.Param("id"),
+		Type:      req.Type,
+		Amount:    req.Amount,
+		Reason:    req.Reason,
+		OrderID:   req.OrderID,
+	})
+	if err != nil {
+		l.error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, entry)
+}
+
+func (l *LedgerController) Balance(ctx *gin.Context) {
+	balance, err := l.ledgerService.Balance(ctx, ctx.Param("id"))
+	if err != nil {
+		l.error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, balance)
+}
+
+func (l *LedgerController) error(ctx *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, cservice.ErrCourierNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidEntry):
+		status = http.StatusBadRequest
+	}
+
+	ctx.JSON(status, gin.H{"error": err.Error()})
+}
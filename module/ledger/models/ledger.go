@@ -0,0 +1,44 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// EntryType тип записи в журнале начислений курьера
+type EntryType string
+
+const (
+	EntryPayout     EntryType = "payout"     // оплата доставки заказа
+	EntryBonus      EntryType = "bonus"      // бонус
+	EntryPenalty    EntryType = "penalty"    // штраф, сумма отрицательная
+	EntryAdjustment EntryType = "adjustment" // корректировка любого знака
+)
+
+// Entry запись журнала начислений. Суммы хранятся в копейках, чтобы баланс считался точно
+type Entry struct {
+	ID        string    `json:"id"`
+	CourierID string    `json:"courier_id"`
+	Type      EntryType `json:"type"`
+	Amount    int64     `json:"amount"` // сумма в копейках, списания отрицательные
+	Reason    string    `json:"reason"`
+	OrderID   int64     `json:"order_id,omitempty"` // заказ, за который сделана запись
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Page страница журнала начислений, записи от новых к старым
+type Page struct {
+	Entries []Entry `json:"entries"`
+	Next    string  `json:"next,omitempty"` // курсор before для следующей страницы, пусто - записей больше нет
+}
+
+// Balance баланс курьера в копейках
+type Balance struct {
+	CourierID string `json:"courier_id"`
+	Balance   int64  `json:"balance"`
+}
+
+// MinorUnits переводит сумму в рублях в копейки с округлением до ближайшей копейки
+func MinorUnits(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/GoGerman/geo-task/cache"
+	"github.com/GoGerman/geo-task/clock"
+	cservice "github.com/GoGerman/geo-task/module/courier/service"
+	"github.com/GoGerman/geo-task/module/ledger/models"
+	"github.com/GoGerman/geo-task/module/ledger/storage"
+	"strings"
+)
+
+const (
+	DefaultLimit = 20  // количество записей на странице журнала по умолчанию
+	MaxLimit     = 100 // максимальное количество записей на странице журнала
+)
+
+var ErrInvalidEntry = errors.New("invalid ledger entry")
+
+type Ledgerer interface {
+	Record(ctx context.Context, entry models.Entry) (*models.Entry, error)                  // добавляет запись в журнал курьера и меняет его баланс, записи не изменяются и не удаляются
+	RecordPayout(ctx context.Context, courierID string, orderID int64, price float64) error // начисляет курьеру оплату доставки заказа
+	PayoutStep(courierID string, orderID int64, price float64) (cache.TxStep, error)        // возвращает шаг транзакции, начисляющий оплату доставки вместе с доставкой, курьер проверяется самой транзакцией
+	Entries(ctx context.Context, courierID, before string, limit int) (*models.Page, error) // возвращает страницу журнала от новых записей к старым, начиная после записи before
+	Balance(ctx context.Context, courierID string) (*models.Balance, error)                 // возвращает баланс курьера в копейках
+}
+
+// LedgerService журнал начислений курьера. Все суммы в копейках, поэтому баланс
+// складывается из целых чисел и не накапливает ошибок округления float64
+type LedgerService struct {
+	storage        storage.LedgerStorager
+	courierService cservice.Courierer
+	clock          clock.Clock
+}
+
+func NewLedgerService(storage storage.LedgerStorager, courierService cservice.Courierer, clock clock.Clock) Ledgerer {
+	return &LedgerService{
+		storage:        storage,
+		courierService: courierService,
+		clock:          clock,
+	}
+}
+
+func (l *LedgerService) Record(ctx context.Context, entry models.Entry) (*models.Entry, error) {
+	entry.Reason = strings.TrimSpace(entry.Reason)
+
+	err := validateEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.checkCourier(ctx, entry.CourierID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Timestamp = l.clock.Now()
+
+	entry.ID, err = l.storage.Append(ctx, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+func (l *LedgerService) RecordPayout(ctx context.Context, courierID string, orderID int64, price float64) error {
+	_, err := l.Record(ctx, payout(courierID, orderID, price))
+
+	return err
+}
+
+func (l *LedgerService) PayoutStep(courierID string, orderID int64, price float64) (cache.TxStep, error) {
+	entry := payout(courierID, orderID, price)
+
+	err := validateEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Timestamp = l.clock.Now()
+
+	return l.storage.AppendStep(entry)
+}
+
+// payout возвращает запись об оплате доставки заказа orderID
+func payout(courierID string, orderID int64, price float64) models.Entry {
+	return models.Entry{
+		CourierID: courierID,
+		Type:      models.EntryPayout,
+		Amount:    models.MinorUnits(price),
+		Reason:    fmt.Sprintf("delivery of order %d", orderID),
+		OrderID:   orderID,
+	}
+}
+
+func (l *LedgerService) Entries(ctx context.Context, courierID, before string, limit int) (*models.Page, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	err := l.checkCourier(ctx, courierID)
+	if err != nil {
+		return nil, err
+	}
+
+	// читаем на одну запись больше, чтобы понять, есть ли следующая страница
+	entries, err := l.storage.Range(ctx, courierID, before, int64(limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.Page{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.Next = entries[limit-1].ID
+	}
+
+	return page, nil
+}
+
+func (l *LedgerService) Balance(ctx context.Context, courierID string) (*models.Balance, error) {
+	err := l.checkCourier(ctx, courierID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := l.storage.Balance(ctx, courierID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Balance{CourierID: courierID, Balance: balance}, nil
+}
+
+// checkCourier возвращает ErrCourierNotFound, если курьера нет. Курьер только проверяется, а не читается,
+// поэтому запись в журнал не меняет его положение
+func (l *LedgerService) checkCourier(ctx context.Context, courierID string) error {
+	exists, err := l.courierService.Exists(ctx, courierID)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("%w: %s", cservice.ErrCourierNotFound, courierID)
+	}
+
+	return nil
+}
+
+// validateEntry проверяет тип записи и знак суммы: оплата и бонус только начисляют,
+// штраф только списывает, корректировка может быть любого знака, но не нулевой
+func validateEntry(entry models.Entry) error {
+	switch entry.Type {
+	case models.EntryPayout, models.EntryBonus:
+		if entry.Amount <= 0 {
+			return fmt.Errorf("%w: %s amount must be positive", ErrInvalidEntry, entry.Type)
+		}
+	case models.EntryPenalty:
+		if entry.Amount >= 0 {
+			return fmt.Errorf("%w: %s amount must be negative", ErrInvalidEntry, entry.Type)
+		}
+	case models.EntryAdjustment:
+		if entry.Amount == 0 {
+			return fmt.Errorf("%w: %s amount must not be zero", ErrInvalidEntry, entry.Type)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidEntry, entry.Type)
+	}
+
+	if entry.Reason == "" {
+		return fmt.Errorf("%w: reason is required", ErrInvalidEntry)
+	}
+
+	return nil
+}
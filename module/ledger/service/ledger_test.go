@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/GoGerman/geo-task/internal/testutil"
+	cmodels "github.com/GoGerman/geo-task/module/courier/models"
+	cservice "github.com/GoGerman/geo-task/module/courier/service"
+	cstorage "github.com/GoGerman/geo-task/module/courier/storage"
+	"github.com/GoGerman/geo-task/module/ledger/models"
+	"github.com/GoGerman/geo-task/module/ledger/storage"
+	"testing"
+)
+
+// newTestLedgerService возвращает журнал и хранилище курьеров, в котором есть курьер "1" вне разрешенной зоны
+func newTestLedgerService(t *testing.T) (Ledgerer, cstorage.CourierStorager) {
+	t.Helper()
+
+	env := testutil.NewEnv(t)
+	courierStorage := env.CourierStorage()
+
+	err := courierStorage.Save(context.Background(), cmodels.Courier{ID: "1", Location: cmodels.Point{Lat: 10, Lng: 10}, State: cmodels.CourierOffline})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewLedgerService(storage.NewLedgerStorage(env.Client), env.Couriers(testutil.CourierOptions{}), env.Clock), courierStorage
+}
+
+func TestRecordRejectsInvalidEntries(t *testing.T) {
+	ledger, _ := newTestLedgerService(t)
+
+	tests := []struct {
+		name  string
+		entry models.Entry
+	}{
+		{name: "negative payout", entry: models.Entry{Type: models.EntryPayout, Amount: -100, Reason: "delivery"}},
+		{name: "zero bonus", entry: models.Entry{Type: models.EntryBonus, Reason: "bonus"}},
+		{name: "positive penalty", entry: models.Entry{Type: models.EntryPenalty, Amount: 100, Reason: "late"}},
+		{name: "zero adjustment", entry: models.Entry{Type: models.EntryAdjustment, Reason: "fix"}},
+		{name: "unknown type", entry: models.Entry{Type: "tip", Amount: 100, Reason: "tip"}},
+		{name: "blank reason", entry: models.Entry{Type: models.EntryBonus, Amount: 100, Reason: "  "}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.entry.CourierID = "1"
+
+			_, err := ledger.Record(context.Background(), tt.entry)
+			if !errors.Is(err, ErrInvalidEntry) {
+				t.Errorf("Record() error = %v, want %v", err, ErrInvalidEntry)
+			}
+		})
+	}
+}
+
+func TestUnknownCourierIsNotFound(t *testing.T) {
+	ctx := context.Background()
+	ledger, _ := newTestLedgerService(t)
+
+	_, err := ledger.Record(ctx, models.Entry{CourierID: "404", Type: models.EntryBonus, Amount: 100, Reason: "bonus"})
+	if !errors.Is(err, cservice.ErrCourierNotFound) {
+		t.Errorf("Record() error = %v, want %v", err, cservice.ErrCourierNotFound)
+	}
+
+	_, err = ledger.Entries(ctx, "404", "", 0)
+	if !errors.Is(err, cservice.ErrCourierNotFound) {
+		t.Errorf("Entries() error = %v, want %v", err, cservice.ErrCourierNotFound)
+	}
+
+	_, err = ledger.Balance(ctx, "404")
+	if !errors.Is(err, cservice.ErrCourierNotFound) {
+		t.Errorf("Balance() error = %v, want %v", err, cservice.ErrCourierNotFound)
+	}
+}
+
+func TestRecordDoesNotChangeCourier(t *testing.T) {
+	ctx := context.Background()
+	ledger, couriers := newTestLedgerService(t)
+
+	err := ledger.RecordPayout(ctx, "1", 7, 123.456)
+	if err != nil {
+		t.Fatalf("RecordPayout() error = %v", err)
+	}
+
+	// GetCourier перенес бы курьера вне разрешенной зоны в нее
+	courier, err := couriers.GetByID(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if courier.Location != (cmodels.Point{Lat: 10, Lng: 10}) {
+		t.Errorf("Location = %v, want unchanged", courier.Location)
+	}
+
+	balance, err := ledger.Balance(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Balance != 12346 {
+		t.Errorf("Balance = %d, want 12346", balance.Balance)
+	}
+}
+
+func TestEntriesPagesFromNewest(t *testing.T) {
+	ctx := context.Background()
+	ledger, _ := newTestLedgerService(t)
+
+	amounts := []int64{100, 200, -50, 300, 25}
+	var want int64
+	for _, amount := range amounts {
+		entry := models.Entry{CourierID: "1", Type: models.EntryAdjustment, Amount: amount, Reason: "fix"}
+
+		_, err := ledger.Record(ctx, entry)
+		if err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+		want += amount
+	}
+
+	balance, err := ledger.Balance(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Balance != want {
+		t.Errorf("Balance = %d, want %d", balance.Balance, want)
+	}
+
+	var got []int64
+	var sizes []int
+	before := ""
+	for {
+		page, err := ledger.Entries(ctx, "1", before, 2)
+		if err != nil {
+			t.Fatalf("Entries() error = %v", err)
+		}
+
+		sizes = append(sizes, len(page.Entries))
+		for i := range page.Entries {
+			got = append(got, page.Entries[i].Amount)
+		}
+
+		if page.Next == "" {
+			break
+		}
+		before = page.Next
+	}
+
+	if len(sizes) != 3 || sizes[0] != 2 || sizes[1] != 2 || sizes[2] != 1 {
+		t.Errorf("page sizes = %v, want [2 2 1]", sizes)
+	}
+
+	if len(got) != len(amounts) {
+		t.Fatalf("got %d entries, want %d", len(got), len(amounts))
+	}
+	for i := range got {
+		if got[i] != amounts[len(amounts)-1-i] {
+			t.Fatalf("entries = %v, want reverse of %v", got, amounts)
+		}
+	}
+}
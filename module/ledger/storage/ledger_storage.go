@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/GoGerman/geo-task/cache"
+	"github.com/GoGerman/geo-task/module/ledger/models"
+	"github.com/redis/go-redis/v9"
+)
+
+type LedgerStorager interface {
+	Append(ctx context.Context, entry models.Entry) (string, error)                           // добавить запись в журнал и изменить баланс одной транзакцией, возвращает id записи
+	AppendStep(entry models.Entry) (cache.TxStep, error)                                      // шаг транзакции, добавляющий запись в журнал и меняющий баланс
+	Range(ctx context.Context, courierID, before string, count int64) ([]models.Entry, error) // получить count записей от новых к старым, before - id записи, с которой начать, не включая ее
+	Balance(ctx context.Context, courierID string) (int64, error)                             // получить баланс курьера в копейках
+}
+
+// LedgerStorage журнал каждого курьера хранится в redis stream без ограничения длины,
+// записи только добавляются. Баланс ведется отдельным счетчиком, чтобы не суммировать весь журнал
+type LedgerStorage struct {
+	storage *redis.Client
+}
+
+func NewLedgerStorage(storage *redis.Client) LedgerStorager {
+	return &LedgerStorage{storage: storage}
+}
+
+// LedgerKey возвращает ключ стрима с журналом начислений курьера
+func LedgerKey(courierID string) string {
+	return "courier:" + courierID + ":ledger"
+}
+
+// BalanceKey возвращает ключ баланса курьера в копейках
+func BalanceKey(courierID string) string {
+	return "courier:" + courierID + ":balance"
+}
+
+func (s *LedgerStorage) Append(ctx context.Context, entry models.Entry) (string, error) {
+	var id *redis.StringCmd
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.storage.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		id = queueAppend(ctx, pipe, entry, data)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return id.Val(), nil
+}
+
+func (s *LedgerStorage) AppendStep(entry models.Entry) (cache.TxStep, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return cache.QueueStep(func(ctx context.Context, pipe redis.Pipeliner) {
+		queueAppend(ctx, pipe, entry, data)
+	}), nil
+}
+
+// queueAppend добавляет в MULTI запись data в журнал курьера и изменение его баланса
+func queueAppend(ctx context.Context, pipe redis.Pipeliner, entry models.Entry, data []byte) *redis.StringCmd {
+	id := pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: LedgerKey(entry.CourierID),
+		Values: map[string]interface{}{
+			"entry": data,
+		},
+	})
+	pipe.IncrBy(ctx, BalanceKey(entry.CourierID), entry.Amount)
+
+	return id
+}
+
+func (s *LedgerStorage) Range(ctx context.Context, courierID, before string, count int64) ([]models.Entry, error) {
+	end := "+"
+	if before != "" {
+		end = "(" + before
+	}
+
+	messages, err := s.storage.XRevRangeN(ctx, LedgerKey(courierID), end, "-", count).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.Entry, 0, len(messages))
+	for i := range messages {
+		data, ok := messages[i].Values["entry"].(string)
+		if !ok {
+			continue
+		}
+
+		var entry models.Entry
+		err = json.Unmarshal([]byte(data), &entry)
+		if err != nil {
+			return nil, err
+		}
+
+		// id записи - id сообщения в стриме, он же курсор для постраничного чтения
+		entry.ID = messages[i].ID
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func (s *LedgerStorage) Balance(ctx context.Context, courierID string) (int64, error) {
+	balance, err := s.storage.Get(ctx, BalanceKey(courierID)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+
+	return balance, err
+}
@@ -3,11 +3,27 @@ package models
 import "time"
 
 type Order struct {
-	ID            int64     `json:"id"`
-	Price         float64   `json:"price"`
-	DeliveryPrice float64   `json:"delivery_price"`
-	Lng           float64   `json:"lng"`
-	Lat           float64   `json:"lat"`
-	IsDelivered   bool      `json:"is_delivered"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID            int64   `json:"id"`
+	Price         float64 `json:"price"`
+	DeliveryPrice float64 `json:"delivery_price"`
+	// точка, в которой курьер забирает заказ
+	Lng float64 `json:"lng"`
+	Lat float64 `json:"lat"`
+	// точка, в которую курьер доставляет заказ
+	DropoffLng  float64   `json:"dropoff_lng"`
+	DropoffLat  float64   `json:"dropoff_lat"`
+	IsPickedUp  bool      `json:"is_picked_up"`
+	IsDelivered bool      `json:"is_delivered"`
+	CourierID   string    `json:"courier_id,omitempty"` // курьер, который забрал заказ
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Dropoff возвращает точку доставки заказа в порядке lat, lng.
+// Заказы, созданные до появления точки доставки, доставляются в точку получения
+func (o Order) Dropoff() (float64, float64) {
+	if o.DropoffLat == 0 && o.DropoffLng == 0 {
+		return o.Lat, o.Lng
+	}
+
+	return o.DropoffLat, o.DropoffLng
 }
@@ -2,10 +2,15 @@ package service
 
 import (
 	"context"
+	"errors"
+	"github.com/GoGerman/geo-task/cache"
+	"github.com/GoGerman/geo-task/clock"
 	"github.com/GoGerman/geo-task/geo"
 	"github.com/GoGerman/geo-task/module/order/models"
 	"github.com/GoGerman/geo-task/module/order/storage"
-	"math/rand"
+	"github.com/GoGerman/geo-task/random"
+	"math"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,27 +22,77 @@ const (
 	minOrderPrice = 1000.00
 
 	orderMaxAge = 2 * time.Minute
+
+	// carriedOrderMaxAge время жизни заказа после того, как курьер его забрал
+	carriedOrderMaxAge = 2 * time.Hour
+
+	// maxDropoffDistance максимальное расстояние в метрах от точки получения до точки доставки заказа
+	maxDropoffDistance = 1500.0
+	// dropoffAttempts количество попыток выбрать точку доставки в разрешенной зоне рядом с точкой получения
+	dropoffAttempts = 10
+
+	// orderPointAttempts количество попыток выбрать точку заказа вне зон, в которых достигнут лимит заказов
+	orderPointAttempts = 10
+
+	// metersPerDegree длина одного градуса широты в метрах
+	metersPerDegree = geo.EarthRadius * math.Pi / 180
 )
 
+var (
+	// ErrOrderTaken заказ уже забран другим курьером или удален по времени жизни
+	ErrOrderTaken = storage.ErrOrderTaken
+	// ErrOrderNotCarried заказ уже доставлен, его везет другой курьер или он удален по времени жизни
+	ErrOrderNotCarried = storage.ErrOrderNotCarried
+)
+
+// ErrOrderLimitReached все выбранные точки попали в зоны, в которых достигнут лимит заказов max_orders
+var ErrOrderLimitReached = errors.New("order limit reached in zone")
+
 type Orderer interface {
 	GetByRadius(ctx context.Context, lng, lat, radius float64, unit string) ([]models.Order, error) // возвращает заказы через метод storage.GetByRadius
 	Save(ctx context.Context, order models.Order) error                                             // сохраняет заказ через метод storage.Save с заданным временем жизни OrderMaxAge
 	GetCount(ctx context.Context) (int, error)                                                      // возвращает количество заказов через метод storage.GetCount
 	RemoveOldOrders(ctx context.Context) error                                                      // удаляет старые заказы через метод storage.RemoveOldOrders с заданным временем жизни OrderMaxAge
-	GenerateOrder(ctx context.Context) error                                                        // генерирует заказ в случайной точке из разрешенной зоны, с уникальным id, ценой и ценой доставки
+	GenerateOrder(ctx context.Context) error                                                        // генерирует заказ в случайной точке из разрешенной зоны с учетом лимита заказов зоны, с уникальным id, ценой и ценой доставки
+	GetByID(ctx context.Context, orderID int64) (*models.Order, error)                              // возвращает заказ по id, nil если заказа нет
+	ClaimOrder(orderID int64, courierID string) cache.TxStep                                        // возвращает шаг транзакции курьера, закрепляющий заказ за ним через метод storage.Claim
+	DeliverOrder(orderID int64, courierID string) cache.TxStep                                      // возвращает шаг транзакции курьера, отмечающий заказ доставленным через метод storage.Deliver
 }
 
 // OrderService реализация интерфейса Orderer
 // в нем должны быть методы GetByRadius, Save, GetCount, RemoveOldOrders, GenerateOrder
 // данный сервис отвечает за работу с заказами
+// IDSource источник уникальных id заказов
+type IDSource interface {
+	GenerateUniqueID(ctx context.Context) (int64, error)
+}
+
+// Sequence id заказов по порядку с 1 в пределах процесса. В отличие от счетчика в redis
+// не зависит от заказов прошлых запусков, поэтому подходит для воспроизведения симуляции одним инстансом
+type Sequence struct {
+	last int64
+}
+
+func NewSequence() *Sequence {
+	return &Sequence{}
+}
+
+func (s *Sequence) GenerateUniqueID(ctx context.Context) (int64, error) {
+	return atomic.AddInt64(&s.last, 1), nil
+}
+
 type OrderService struct {
-	storage       storage.OrderStorager
-	allowedZone   geo.PolygonChecker
-	disabledZones []geo.PolygonChecker
+	storage storage.OrderStorager
+	zones   geo.ZoneProvider
+	clock   clock.Clock
+	rand    random.Rand
+	ids     IDSource
 }
 
-func NewOrderService(storage storage.OrderStorager, allowedZone geo.PolygonChecker, disallowedZone []geo.PolygonChecker) Orderer {
-	return &OrderService{storage: storage, allowedZone: allowedZone, disabledZones: disallowedZone}
+// NewOrderService clock, rand и ids задают время создания, параметры и id заказов,
+// при одинаковых seed, часах и источнике id генерация заказов воспроизводится
+func NewOrderService(storage storage.OrderStorager, zones geo.ZoneProvider, clock clock.Clock, rand random.Rand, ids IDSource) Orderer {
+	return &OrderService{storage: storage, zones: zones, clock: clock, rand: rand, ids: ids}
 }
 
 func (o *OrderService) GetByRadius(ctx context.Context, lng, lat, radius float64, unit string) ([]models.Order, error) {
@@ -56,18 +111,36 @@ func (o *OrderService) RemoveOldOrders(ctx context.Context) error {
 	return o.storage.RemoveOldOrders(ctx, orderMaxAge)
 }
 
+func (o *OrderService) GetByID(ctx context.Context, orderID int64) (*models.Order, error) {
+	return o.storage.GetByID(ctx, int(orderID))
+}
+
+func (o *OrderService) ClaimOrder(orderID int64, courierID string) cache.TxStep {
+	return o.storage.Claim(orderID, courierID, carriedOrderMaxAge)
+}
+
+func (o *OrderService) DeliverOrder(orderID int64, courierID string) cache.TxStep {
+	return o.storage.Deliver(orderID, courierID)
+}
+
 func (o *OrderService) GenerateOrder(ctx context.Context) error {
 	var err error
 	var orderID int64
 
-	orderID, err = o.storage.GenerateUniqueID(ctx)
+	zones := o.zones.Zones()
+	point, err := o.orderPoint(ctx, zones)
 	if err != nil {
 		return err
 	}
 
-	point := geo.GetRandomAllowedLocation(o.allowedZone, o.disabledZones)
-	price := minOrderPrice + rand.Float64()*(maxOrderPrice-minOrderPrice)
-	deliveryPrice := minDeliveryPrice + rand.Float64()*(maxDeliveryPrice-minDeliveryPrice)
+	orderID, err = o.ids.GenerateUniqueID(ctx)
+	if err != nil {
+		return err
+	}
+
+	price := minOrderPrice + o.rand.Float64()*(maxOrderPrice-minOrderPrice)
+	deliveryPrice := o.deliveryPrice(zones.AttributesAt(point))
+	dropoff := o.dropoffPoint(zones, point)
 
 	order := models.Order{
 		ID:            orderID,
@@ -75,8 +148,10 @@ func (o *OrderService) GenerateOrder(ctx context.Context) error {
 		DeliveryPrice: deliveryPrice,
 		Lng:           point.Lng,
 		Lat:           point.Lat,
+		DropoffLng:    dropoff.Lng,
+		DropoffLat:    dropoff.Lat,
 		IsDelivered:   false,
-		CreatedAt:     time.Now(),
+		CreatedAt:     o.clock.Now(),
 	}
 
 	err = o.storage.Save(ctx, order, orderMaxAge)
@@ -86,3 +161,116 @@ func (o *OrderService) GenerateOrder(ctx context.Context) error {
 
 	return nil
 }
+
+// orderPoint выбирает случайную разрешенную точку вне зон, в которых уже достигнут лимит заказов
+func (o *OrderService) orderPoint(ctx context.Context, zones *geo.ZoneSet) (geo.Point, error) {
+	for i := 0; i < orderPointAttempts; i++ {
+		point, err := geo.GetRandomAllowedLocation(zones.Allowed, zones.Disabled, o.rand)
+		if err != nil {
+			return geo.Point{}, err
+		}
+
+		full, err := o.zoneLimitReached(ctx, zones.AttributedZonesAt(point))
+		if err != nil {
+			return geo.Point{}, err
+		}
+
+		if !full {
+			return point, nil
+		}
+	}
+
+	return geo.Point{}, ErrOrderLimitReached
+}
+
+// zoneLimitReached проверяет, достигнут ли лимит заказов хотя бы в одной из зон
+func (o *OrderService) zoneLimitReached(ctx context.Context, zones []geo.AttributedZone) (bool, error) {
+	for i := range zones {
+		limit, ok := zones[i].Attributes[geo.AttributeMaxOrders]
+		if !ok {
+			continue
+		}
+
+		count, err := o.countInZone(ctx, zones[i].Zone)
+		if err != nil {
+			return false, err
+		}
+
+		if count >= int(limit) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// countInZone считает доступные заказы в зоне: заказы из прямоугольника, описанного вокруг зоны,
+// дополнительно проверяются попаданием в полигон
+func (o *OrderService) countInZone(ctx context.Context, zone geo.PolygonChecker) (int, error) {
+	b := zone.Bounds()
+
+	// ширина прямоугольника берется по широте, ближайшей к экватору, чтобы он покрывал всю зону
+	lat := math.Min(math.Abs(b.Min.Lat), math.Abs(b.Max.Lat))
+	if b.Min.Lat <= 0 && b.Max.Lat >= 0 {
+		lat = 0
+	}
+
+	// запас в один метр покрывает погрешность координат гео индекса
+	width := (b.Max.Lng-b.Min.Lng)*metersPerDegree*math.Cos(lat*math.Pi/180) + 1
+	height := (b.Max.Lat-b.Min.Lat)*metersPerDegree + 1
+
+	locations, err := o.storage.GetLocationsInBox(ctx, (b.Min.Lng+b.Max.Lng)/2, (b.Min.Lat+b.Max.Lat)/2, width, height, "m")
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := range locations {
+		if zone.Contains(geo.Point{Lat: locations[i].Latitude, Lng: locations[i].Longitude}) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// dropoffPoint выбирает точку доставки в разрешенной зоне не дальше maxDropoffDistance от точки получения.
+// Если за dropoffAttempts попыток такой точки не нашлось, выбирается любая разрешенная точка
+func (o *OrderService) dropoffPoint(zones *geo.ZoneSet, pickup geo.Point) geo.Point {
+	for i := 0; i < dropoffAttempts; i++ {
+		distance := o.rand.Float64() * maxDropoffDistance
+		bearing := o.rand.Float64() * 2 * math.Pi
+
+		// смещение в метрах переводится в градусы, для расстояний в пределах города сферичностью можно пренебречь
+		point := geo.Point{
+			Lat: pickup.Lat + distance*math.Cos(bearing)/metersPerDegree,
+			Lng: pickup.Lng + distance*math.Sin(bearing)/(metersPerDegree*math.Cos(pickup.Lat*math.Pi/180)),
+		}
+
+		if geo.CheckPointIsAllowed(point, zones.Allowed, zones.Disabled) {
+			return point
+		}
+	}
+
+	point, err := geo.GetRandomAllowedLocation(zones.Allowed, zones.Disabled, o.rand)
+	if err != nil {
+		return pickup
+	}
+
+	return point
+}
+
+// deliveryPrice рассчитывает стоимость доставки по атрибутам зоны, в которую попал заказ.
+// Если атрибуты не заданы, используется диапазон по умолчанию
+func (o *OrderService) deliveryPrice(attributes geo.Attributes) float64 {
+	minPrice := attributes.Value(geo.AttributeMinDeliveryPrice, minDeliveryPrice)
+	maxPrice := attributes.Value(geo.AttributeMaxDeliveryPrice, maxDeliveryPrice)
+	multiplier := attributes.Value(geo.AttributePriceMultiplier, 1)
+
+	// зона может задать только одну границу, которая выходит за диапазон по умолчанию
+	if maxPrice < minPrice {
+		maxPrice = minPrice
+	}
+
+	return (minPrice + o.rand.Float64()*(maxPrice-minPrice)) * multiplier
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/GoGerman/geo-task/clock"
+	"github.com/GoGerman/geo-task/geo"
+	"github.com/GoGerman/geo-task/internal/testutil"
+	"github.com/GoGerman/geo-task/module/order/models"
+	"github.com/GoGerman/geo-task/module/order/storage"
+	"github.com/GoGerman/geo-task/random"
+	"testing"
+	"time"
+)
+
+// cityGeometry город 0.2 x 0.1 градуса, west - его западная половина
+var (
+	cityGeometry = geo.Geometry{Type: geo.GeometryPolygon, Coordinates: []byte(`[[[30.0,59.9],[30.2,59.9],[30.2,60.0],[30.0,60.0],[30.0,59.9]]]`)}
+	westGeometry = geo.Geometry{Type: geo.GeometryPolygon, Coordinates: []byte(`[[[30.0,59.9],[30.1,59.9],[30.1,60.0],[30.0,60.0],[30.0,59.9]]]`)}
+)
+
+func newTestOrderService(t *testing.T, specs []geo.ZoneSpec) (Orderer, storage.OrderStorager) {
+	t.Helper()
+
+	env := testutil.NewEnv(t, specs...)
+	orderStorage := storage.NewOrderStorage(env.Client, env.Clock)
+
+	return NewOrderService(orderStorage, env.Zones, env.Clock, random.New(1), orderStorage), orderStorage
+}
+
+func TestGenerateOrderUsesZoneAttributes(t *testing.T) {
+	ctx := context.Background()
+	orders, orderStorage := newTestOrderService(t, []geo.ZoneSpec{
+		{ID: "city", Allowed: true, Geometry: cityGeometry},
+		{ID: "west", Overlay: true, Geometry: westGeometry, Attributes: geo.Attributes{
+			geo.AttributeMinDeliveryPrice: 100,
+			geo.AttributeMaxDeliveryPrice: 100,
+			geo.AttributePriceMultiplier:  2,
+			geo.AttributeMaxOrders:        3,
+		}},
+	})
+
+	for i := 0; i < 40; i++ {
+		err := orders.GenerateOrder(ctx)
+		if err != nil && !errors.Is(err, ErrOrderLimitReached) {
+			t.Fatalf("GenerateOrder() error = %v", err)
+		}
+	}
+
+	// id заказов выдаются подряд, поэтому все созданные заказы можно получить по id
+	var generated []models.Order
+	for id := 1; ; id++ {
+		order, err := orderStorage.GetByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if order == nil {
+			break
+		}
+		generated = append(generated, *order)
+	}
+
+	west := 0
+	for _, order := range generated {
+		if order.Lng < 30.1 {
+			west++
+			if order.DeliveryPrice != 200 {
+				t.Errorf("order %d in west: delivery price = %v, want 200", order.ID, order.DeliveryPrice)
+			}
+			continue
+		}
+
+		if order.DeliveryPrice < minDeliveryPrice || order.DeliveryPrice > maxDeliveryPrice {
+			t.Errorf("order %d outside districts: delivery price = %v, want default range", order.ID, order.DeliveryPrice)
+		}
+	}
+
+	if west != 3 {
+		t.Errorf("orders in west = %d, want max_orders 3", west)
+	}
+	if len(generated)-west == 0 {
+		t.Error("no orders generated outside limited district")
+	}
+}
+
+func TestGenerateOrderLimitReached(t *testing.T) {
+	ctx := context.Background()
+	orders, _ := newTestOrderService(t, []geo.ZoneSpec{
+		{ID: "city", Allowed: true, Geometry: cityGeometry},
+		{ID: "closed", Overlay: true, Geometry: cityGeometry, Attributes: geo.Attributes{geo.AttributeMaxOrders: 0}},
+	})
+
+	err := orders.GenerateOrder(ctx)
+	if !errors.Is(err, ErrOrderLimitReached) {
+		t.Fatalf("GenerateOrder() error = %v, want %v", err, ErrOrderLimitReached)
+	}
+
+	count, err := orders.GetCount(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("GetCount() = %d, want 0", count)
+	}
+}
+
+// recordedIDs запоминает выданные id заказов
+type recordedIDs struct {
+	IDSource
+	ids []int64
+}
+
+func (r *recordedIDs) GenerateUniqueID(ctx context.Context) (int64, error) {
+	id, err := r.IDSource.GenerateUniqueID(ctx)
+	if err == nil {
+		r.ids = append(r.ids, id)
+	}
+
+	return id, err
+}
+
+// generateOrders воспроизводит count заказов с заданным seed, как приложение с SIM_SEED, SIM_START и SIM_STEP,
+// и возвращает их в порядке id. При withCouriers между заказами выбираются случайные точки для курьеров,
+// как при их появлении в симуляции
+func generateOrders(t *testing.T, env *testutil.Env, seed int64, count int, withCouriers bool) []models.Order {
+	t.Helper()
+
+	clk := clock.NewStepped(testutil.Start, 10*time.Millisecond)
+	set := env.Zones.Zones()
+	ids := &recordedIDs{IDSource: NewSequence()}
+	orders := NewOrderService(storage.NewOrderStorage(env.Client, clk), env.Zones, clk, random.Derive(seed, "orders"), ids)
+	couriers := random.Derive(seed, "couriers")
+
+	ctx := context.Background()
+	res := make([]models.Order, 0, count)
+	for i := 0; i < count; i++ {
+		if withCouriers {
+			_, err := geo.GetRandomAllowedLocation(set.Allowed, set.Disabled, couriers)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err := orders.GenerateOrder(ctx)
+		if err != nil {
+			t.Fatalf("GenerateOrder() error = %v", err)
+		}
+
+		id := ids.ids[len(ids.ids)-1]
+		order, err := orders.GetByID(ctx, id)
+		if err != nil || order == nil {
+			t.Fatalf("GetByID(%d) = %v, %v", id, order, err)
+		}
+		res = append(res, *order)
+	}
+
+	return res
+}
+
+// newGeneratorEnv окружение с городом и запрещенным парком
+func newGeneratorEnv(t *testing.T) *testutil.Env {
+	return testutil.NewEnv(t,
+		geo.ZoneSpec{ID: "city", Allowed: true, Geometry: cityGeometry},
+		geo.ZoneSpec{ID: "park", Geometry: geo.Geometry{Type: geo.GeometryPolygon, Coordinates: []byte(`[[[30.05,59.92],[30.1,59.92],[30.1,59.97],[30.05,59.97],[30.05,59.92]]]`)}},
+	)
+}
+
+func TestGenerateOrderIsReproducible(t *testing.T) {
+	env := newGeneratorEnv(t)
+
+	first, err := json.Marshal(generateOrders(t, env, 42, 100, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// второй запуск идет в том же redis, где остались заказы первого запуска,
+	// а случайные точки курьеров берутся из своего генератора и не меняют заказы
+	second, err := json.Marshal(generateOrders(t, env, 42, 100, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(first, second) {
+		t.Fatalf("orders differ for the same seed:\n%s\n%s", first, second)
+	}
+
+	other, err := json.Marshal(generateOrders(t, newGeneratorEnv(t), 43, 100, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first, other) {
+		t.Error("orders are the same for different seeds")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/GoGerman/geo-task/cache"
+	"github.com/GoGerman/geo-task/clock"
 	"github.com/GoGerman/geo-task/module/order/models"
 	"github.com/redis/go-redis/v9"
 	"time"
@@ -15,21 +17,32 @@ const OrderKeyPrefix = "order"
 const OrdersGeoDataKey = "orders:geo"
 const OrdersSetKey = "orders"
 
+var (
+	// ErrOrderTaken заказ уже забран другим курьером или удален по времени жизни
+	ErrOrderTaken = errors.New("order already taken")
+	// ErrOrderNotCarried заказ уже доставлен, его везет другой курьер или он удален по времени жизни
+	ErrOrderNotCarried = errors.New("order is not carried by courier")
+)
+
 type OrderStorager interface {
-	Save(ctx context.Context, order models.Order, maxAge time.Duration) error                       // сохранить заказ с временем жизни
-	GetByID(ctx context.Context, orderID int) (*models.Order, error)                                // получить заказ по id
-	GenerateUniqueID(ctx context.Context) (int64, error)                                            // сгенерировать уникальный id
-	GetByRadius(ctx context.Context, lng, lat, radius float64, unit string) ([]models.Order, error) // получить заказы в радиусе от точки
-	GetCount(ctx context.Context) (int, error)                                                      // получить количество заказов
-	RemoveOldOrders(ctx context.Context, maxAge time.Duration) error                                // удалить старые заказы по истечению времени maxAge
+	Save(ctx context.Context, order models.Order, maxAge time.Duration) error                                         // сохранить заказ с временем жизни
+	GetByID(ctx context.Context, orderID int) (*models.Order, error)                                                  // получить заказ по id
+	GenerateUniqueID(ctx context.Context) (int64, error)                                                              // сгенерировать уникальный id
+	GetByRadius(ctx context.Context, lng, lat, radius float64, unit string) ([]models.Order, error)                   // получить заказы в радиусе от точки
+	GetLocationsInBox(ctx context.Context, lng, lat, width, height float64, unit string) ([]redis.GeoLocation, error) // получить координаты заказов в прямоугольнике с центром в точке
+	GetCount(ctx context.Context) (int, error)                                                                        // получить количество заказов
+	RemoveOldOrders(ctx context.Context, maxAge time.Duration) error                                                  // удалить старые заказы по истечению времени maxAge
+	Claim(orderID int64, courierID string, maxAge time.Duration) cache.TxStep                                         // шаг транзакции, закрепляющий заказ за курьером и убирающий его из индексов, ErrOrderTaken если заказ уже забран
+	Deliver(orderID int64, courierID string) cache.TxStep                                                             // шаг транзакции, отмечающий доставленным заказ, который везет курьер, ErrOrderNotCarried если заказ не у него
 }
 
 type OrderStorage struct {
 	storage *redis.Client
+	clock   clock.Clock
 }
 
-func NewOrderStorage(storage *redis.Client) OrderStorager {
-	return &OrderStorage{storage: storage}
+func NewOrderStorage(storage *redis.Client, clock clock.Clock) OrderStorager {
+	return &OrderStorage{storage: storage, clock: clock}
 }
 
 func (o *OrderStorage) Save(ctx context.Context, order models.Order, maxAge time.Duration) error {
@@ -50,7 +63,7 @@ func (o *OrderStorage) RemoveOldOrders(ctx context.Context, maxAge time.Duration
 	*/
 
 	var err error
-	limitTime := time.Now().Add(-maxAge).Unix()
+	limitTime := o.clock.Now().Add(-maxAge).Unix()
 	max := fmt.Sprintf("%d", limitTime)
 
 	orderList, err := o.storage.ZRangeByScore(ctx, OrdersSetKey, &redis.ZRangeBy{
@@ -89,6 +102,117 @@ func (o *OrderStorage) RemoveOldOrders(ctx context.Context, maxAge time.Duration
 	return nil
 }
 
+func (o *OrderStorage) Claim(orderID int64, courierID string, maxAge time.Duration) cache.TxStep {
+	return &claimStep{
+		key:       fmt.Sprintf("%s:%d", OrderKeyPrefix, orderID),
+		courierID: courierID,
+		maxAge:    maxAge,
+	}
+}
+
+// claimStep закрепляет заказ за курьером в транзакции, в которой сохраняется курьер.
+// Ключ заказа отслеживается через WATCH, поэтому из двух курьеров заказ забирает только один,
+// а если курьер не сохранился, заказ остается доступным
+type claimStep struct {
+	key       string
+	courierID string
+	maxAge    time.Duration
+	data      []byte // заказ, закрепленный за курьером
+}
+
+func (s *claimStep) Keys() []string {
+	return []string{s.key}
+}
+
+func (s *claimStep) Check(ctx context.Context, tx *redis.Tx) error {
+	data, err := tx.Get(ctx, s.key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return ErrOrderTaken
+	}
+	if err != nil {
+		return err
+	}
+
+	var order models.Order
+	err = json.Unmarshal(data, &order)
+	if err != nil {
+		return err
+	}
+
+	if order.IsPickedUp {
+		return ErrOrderTaken
+	}
+
+	order.IsPickedUp = true
+	order.CourierID = s.courierID
+
+	s.data, err = json.Marshal(order)
+
+	return err
+}
+
+func (s *claimStep) Queue(ctx context.Context, pipe redis.Pipeliner) {
+	// время жизни заказа продлевается, чтобы он не истек в пути
+	pipe.Set(ctx, s.key, s.data, s.maxAge)
+	pipe.ZRem(ctx, OrdersGeoDataKey, s.key)
+	pipe.ZRem(ctx, OrdersSetKey, s.key)
+}
+
+func (o *OrderStorage) Deliver(orderID int64, courierID string) cache.TxStep {
+	return &deliverStep{
+		key:       fmt.Sprintf("%s:%d", OrderKeyPrefix, orderID),
+		courierID: courierID,
+	}
+}
+
+// deliverStep отмечает заказ доставленным в транзакции, в которой курьер избавляется от заказа.
+// Меняется только поле is_delivered, остальные поля заказа записываются как были прочитаны,
+// поэтому цена и координаты не теряют точности при повторной сериализации
+type deliverStep struct {
+	key       string
+	courierID string
+	data      []byte // заказ, отмеченный доставленным
+}
+
+func (s *deliverStep) Keys() []string {
+	return []string{s.key}
+}
+
+func (s *deliverStep) Check(ctx context.Context, tx *redis.Tx) error {
+	data, err := tx.Get(ctx, s.key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return ErrOrderNotCarried
+	}
+	if err != nil {
+		return err
+	}
+
+	var order models.Order
+	err = json.Unmarshal(data, &order)
+	if err != nil {
+		return err
+	}
+
+	if order.CourierID != s.courierID || order.IsDelivered {
+		return ErrOrderNotCarried
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
+	}
+	fields["is_delivered"] = json.RawMessage("true")
+
+	s.data, err = json.Marshal(fields)
+
+	return err
+}
+
+func (s *deliverStep) Queue(ctx context.Context, pipe redis.Pipeliner) {
+	pipe.SetArgs(ctx, s.key, s.data, redis.SetArgs{KeepTTL: true})
+}
+
 func (o *OrderStorage) GetByID(ctx context.Context, orderID int) (*models.Order, error) {
 	var err error
 	var data []byte
@@ -220,6 +344,20 @@ func (o *OrderStorage) getOrdersByRadius(ctx context.Context, lng, lat, radius f
 	return locations, nil
 }
 
+func (o *OrderStorage) GetLocationsInBox(ctx context.Context, lng, lat, width, height float64, unit string) ([]redis.GeoLocation, error) {
+	// в гео индексе только доступные заказы, забранные и старые заказы из него удаляются
+	return o.storage.GeoSearchLocation(ctx, OrdersGeoDataKey, &redis.GeoSearchLocationQuery{
+		GeoSearchQuery: redis.GeoSearchQuery{
+			Longitude: lng,
+			Latitude:  lat,
+			BoxWidth:  width,
+			BoxHeight: height,
+			BoxUnit:   unit,
+		},
+		WithCoord: true,
+	}).Result()
+}
+
 func (o *OrderStorage) GenerateUniqueID(ctx context.Context) (int64, error) {
 	var err error
 	var id int64
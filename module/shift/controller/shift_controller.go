@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"errors"
+	cservice "github.com/GoGerman/geo-task/module/courier/service"
+	"github.com/GoGerman/geo-task/module/shift/models"
+	"github.com/GoGerman/geo-task/module/shift/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// ShiftRequest запрос на планирование смены курьера
+type ShiftRequest struct {
+	ZoneID string         `json:"zone_id"`
+	Start  time.Time      `json:"start"` // не задано - смена начинается с текущего момента
+	End    time.Time      `json:"end" binding:"required"`
+	Breaks []models.Break `json:"breaks"`
+}
+
+type ShiftController struct {
+	shiftService service.Shifter
+}
+
+func NewShiftController(shiftService service.Shifter) *ShiftController {
+	return &ShiftController{shiftService: shiftService}
+}
+
+func (s *ShiftController) List(ctx *gin.Context) {
+	shifts, err := s.shiftService.ListShifts(ctx, ctx.Param("id"))
+	if err != nil {
+		s.error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, shifts)
+}
+
+func (s *ShiftController) Get(ctx *gin.Context) {
+	shift, err := s.shiftService.GetShift(ctx, ctx.Param("id"), ctx.Param("shift_id"))
+	if err != nil {
+		s.error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, shift)
+}
+
+func (s *ShiftController) Create(ctx *gin.Context) {
+	var req ShiftRequest
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	shift, err := s.shiftService.CreateShift(ctx, models.Shift{
+		CourierID: ctx.Param("id"),
+		ZoneID:    req.ZoneID,
+		Start:     req.Start,
+		End:       req.End,
+		Breaks:    req.Breaks,
+	})
+	if err != nil {
+		s.error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, shift)
+}
+
+func (s *ShiftController) Start(ctx *gin.Context) {
+	shift, err := s.shiftService.StartShift(ctx, ctx.Param("id"), ctx.Param("shift_id"))
+	if err != nil {
+		s.error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, shift)
+}
+
+func (s *ShiftController) End(ctx *gin.Context) {
+	shift, err := s.shiftService.EndShift(ctx, ctx.Param("id"), ctx.Param("shift_id"))
+	if err != nil {
+		s.error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, shift)
+}
+
+func (s *ShiftController) error(ctx *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrShiftNotFound), errors.Is(err, cservice.ErrCourierNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidShift):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrShiftOverlap), errors.Is(err, service.ErrShiftState), errors.Is(err, cservice.ErrInvalidTransition):
+		status = http.StatusConflict
+	}
+
+	ctx.JSON(status, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Shift смена курьера. Курьер работает только во время начатой и не завершенной смены вне перерывов
+type Shift struct {
+	ID        string  `json:"id"`
+	CourierID string  `json:"courier_id"`
+	ZoneID    string  `json:"zone_id,omitempty"` // зона, в которой курьер видит и забирает заказы, без зоны - весь город
+	Breaks    []Break `json:"breaks,omitempty"`
+	// плановое время начала и окончания смены
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// фактическое время начала и окончания смены, nil - смена еще не начата или не завершена
+	StartedAt *time.Time  `json:"started_at,omitempty"`
+	EndedAt   *time.Time  `json:"ended_at,omitempty"`
+	Report    ShiftReport `json:"report"`
+}
+
+// Break запланированный перерыв внутри смены
+type Break struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// ShiftReport итоги смены
+type ShiftReport struct {
+	Distance   float64 `json:"distance"`   // пройденное расстояние в метрах
+	Deliveries int     `json:"deliveries"` // количество доставленных заказов
+	Earnings   float64 `json:"earnings"`   // сумма стоимости доставки заказов
+}
+
+func (s Shift) MarshalBinary() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// OnBreakAt проверяет, попадает ли момент t в один из перерывов
+func (s Shift) OnBreakAt(t time.Time) bool {
+	for i := range s.Breaks {
+		if !t.Before(s.Breaks[i].Start) && t.Before(s.Breaks[i].End) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ActiveAt проверяет, работает ли курьер по смене в момент t:
+// смена начата, не завершена, плановое окончание не наступило и курьер не на перерыве
+func (s Shift) ActiveAt(t time.Time) bool {
+	if s.StartedAt == nil || s.EndedAt != nil {
+		return false
+	}
+
+	if !t.Before(s.End) {
+		return false
+	}
+
+	return !s.OnBreakAt(t)
+}
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/GoGerman/geo-task/cache"
+	"github.com/GoGerman/geo-task/clock"
+	"github.com/GoGerman/geo-task/geo"
+	cmodels "github.com/GoGerman/geo-task/module/courier/models"
+	cservice "github.com/GoGerman/geo-task/module/courier/service"
+	"github.com/GoGerman/geo-task/module/shift/models"
+	"github.com/GoGerman/geo-task/module/shift/storage"
+	zservice "github.com/GoGerman/geo-task/module/zone/service"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrShiftNotFound = errors.New("shift not found")
+	ErrInvalidShift  = errors.New("invalid shift")
+	ErrShiftOverlap  = errors.New("shift overlaps another shift")
+	ErrShiftState    = errors.New("shift cannot be changed")
+	ErrOffShift      = errors.New("courier is not on an active shift")
+	ErrShiftFinished = errors.New("courier is finishing deliveries after the shift")
+)
+
+type Shifter interface {
+	CreateShift(ctx context.Context, shift models.Shift) (*models.Shift, error)                 // планирует смену курьера, без начала - с текущего момента, смены одного курьера не пересекаются
+	GetShift(ctx context.Context, courierID, id string) (*models.Shift, error)                  // возвращает смену курьера вместе с итогами
+	ListShifts(ctx context.Context, courierID string) ([]models.Shift, error)                   // возвращает смены курьера в порядке планового начала
+	StartShift(ctx context.Context, courierID, id string) (*models.Shift, error)                // начинает смену, курьер выходит на линию
+	EndShift(ctx context.Context, courierID, id string) (*models.Shift, error)                  // завершает смену, курьер уходит с линии
+	Sync(ctx context.Context, courier cmodels.Courier) (*cmodels.Courier, *models.Shift, error) // приводит состояние курьера к его смене, nil смена - курьер не работает, ErrShiftFinished - курьер довозит заказы после смены
+	InZone(shift models.Shift, point cmodels.Point) bool                                        // проверяет, что точка лежит в зоне смены
+	AddDistance(ctx context.Context, id string, distance float64) error                         // добавляет пройденное расстояние в метрах в итоги смены
+	AddDelivery(ctx context.Context, id string, earnings float64) error                         // добавляет доставленный заказ в итоги смены
+	AddDeliveryStep(id string, earnings float64) cache.TxStep                                   // возвращает шаг транзакции, добавляющий доставленный заказ в итоги смены вместе с доставкой
+}
+
+type ShiftService struct {
+	shiftStorage   storage.ShiftStorager
+	courierService cservice.Courierer
+	zoneService    zservice.Zoner
+	clock          clock.Clock
+}
+
+func NewShiftService(shiftStorage storage.ShiftStorager, courierService cservice.Courierer, zoneService zservice.Zoner, clock clock.Clock) Shifter {
+	return &ShiftService{
+		shiftStorage:   shiftStorage,
+		courierService: courierService,
+		zoneService:    zoneService,
+		clock:          clock,
+	}
+}
+
+func (s *ShiftService) CreateShift(ctx context.Context, shift models.Shift) (*models.Shift, error) {
+	exists, err := s.courierService.Exists(ctx, shift.CourierID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, cservice.ErrCourierNotFound
+	}
+
+	// смена без планового начала начинается с текущего момента, так время не зависит от часов клиента
+	if shift.Start.IsZero() {
+		shift.Start = s.clock.Now()
+	}
+
+	err = validateShift(shift)
+	if err != nil {
+		return nil, err
+	}
+
+	if shift.ZoneID != "" {
+		_, err = s.zoneService.GetByID(ctx, shift.ZoneID)
+		if errors.Is(err, zservice.ErrZoneNotFound) {
+			return nil, fmt.Errorf("%w: zone %q not found", ErrInvalidShift, shift.ZoneID)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	id, err := s.shiftStorage.GenerateUniqueID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	shift.ID = strconv.FormatInt(id, 10)
+	shift.StartedAt = nil
+	shift.EndedAt = nil
+	shift.Report = models.ShiftReport{}
+
+	sort.Slice(shift.Breaks, func(i, j int) bool {
+		return shift.Breaks[i].Start.Before(shift.Breaks[j].Start)
+	})
+
+	// пересечение проверяется в одной транзакции с сохранением, поэтому параллельные запросы не создадут пересекающиеся смены
+	err = s.shiftStorage.Insert(ctx, shift, func(shifts []models.Shift) error {
+		for i := range shifts {
+			if shift.Start.Before(shiftEnd(shifts[i])) && shifts[i].Start.Before(shift.End) {
+				return fmt.Errorf("%w %s", ErrShiftOverlap, shifts[i].ID)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &shift, nil
+}
+
+func (s *ShiftService) GetShift(ctx context.Context, courierID, id string) (*models.Shift, error) {
+	shift, err := s.shiftStorage.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// смена другого курьера не отдается, чтобы id из пути не позволял читать чужие смены
+	if shift == nil || shift.CourierID != courierID {
+		return nil, ErrShiftNotFound
+	}
+
+	return shift, nil
+}
+
+func (s *ShiftService) ListShifts(ctx context.Context, courierID string) ([]models.Shift, error) {
+	return s.shiftStorage.List(ctx, courierID)
+}
+
+func (s *ShiftService) StartShift(ctx context.Context, courierID, id string) (*models.Shift, error) {
+	shift, err := s.GetShift(ctx, courierID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+
+	switch {
+	case shift.StartedAt != nil:
+		return nil, fmt.Errorf("%w: shift %s already started", ErrShiftState, shift.ID)
+	case now.Before(shift.Start) || !now.Before(shift.End):
+		return nil, fmt.Errorf("%w: shift %s can be started only between %s and %s",
+			ErrShiftState, shift.ID, shift.Start.Format(time.RFC3339), shift.End.Format(time.RFC3339))
+	}
+
+	shift.StartedAt = &now
+
+	return s.saveAndSync(ctx, *shift)
+}
+
+func (s *ShiftService) EndShift(ctx context.Context, courierID, id string) (*models.Shift, error) {
+	shift, err := s.GetShift(ctx, courierID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case shift.StartedAt == nil:
+		return nil, fmt.Errorf("%w: shift %s not started", ErrShiftState, shift.ID)
+	case shift.EndedAt != nil:
+		return nil, fmt.Errorf("%w: shift %s already ended", ErrShiftState, shift.ID)
+	}
+
+	now := s.clock.Now()
+	shift.EndedAt = &now
+
+	return s.saveAndSync(ctx, *shift)
+}
+
+// saveAndSync сохраняет смену и сразу переводит курьера в состояние, соответствующее смене
+func (s *ShiftService) saveAndSync(ctx context.Context, shift models.Shift) (*models.Shift, error) {
+	err := s.shiftStorage.Save(ctx, shift)
+	if err != nil {
+		return nil, err
+	}
+
+	courier, err := s.courierService.GetCourier(ctx, shift.CourierID)
+	if err != nil {
+		return nil, err
+	}
+
+	// курьер с заказами после завершения смены довозит их, это не ошибка завершения
+	_, _, err = s.Sync(ctx, *courier)
+	if err != nil && !errors.Is(err, ErrShiftFinished) {
+		return nil, err
+	}
+
+	return &shift, nil
+}
+
+// Sync переводит курьера offline с активной сменой в idle, а курьера без заказов вне смены - в offline,
+// в каком бы состоянии он ни был. Курьер с заказами после окончания смены или на перерыве довозит их:
+// Sync возвращает его последнюю смену вместе с ErrShiftFinished, новые заказы такой курьер не получает.
+// Смена заканчивается и перерывы начинаются по времени без отдельных запросов,
+// поэтому состояние курьера проверяется при каждом обращении к нему
+func (s *ShiftService) Sync(ctx context.Context, courier cmodels.Courier) (*cmodels.Courier, *models.Shift, error) {
+	now := s.clock.Now()
+
+	shift, err := s.shiftStorage.Last(ctx, courier.ID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case shift != nil && shift.ActiveAt(now) && courier.State == cmodels.CourierOffline:
+		updated, err := s.courierService.Transition(ctx, courier.ID, cmodels.CourierIdle, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return updated, shift, nil
+	case shift != nil && shift.ActiveAt(now):
+		return &courier, shift, nil
+	case shift != nil && len(courier.Orders) > 0:
+		return &courier, shift, ErrShiftFinished
+	case courier.State == cmodels.CourierOffline:
+		return &courier, nil, nil
+	}
+
+	// курьер мог забрать заказ после того, как был прочитан, тогда Release оставляет его на линии
+	updated, err := s.courierService.Release(ctx, courier.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(updated.Orders) > 0 && shift != nil {
+		return updated, shift, ErrShiftFinished
+	}
+
+	return updated, nil, nil
+}
+
+// InZone проверяет, что точка лежит в зоне смены. Смена без зоны охватывает весь город,
+// а в удаленной после планирования смены зоне курьер больше не получает заказы
+func (s *ShiftService) InZone(shift models.Shift, point cmodels.Point) bool {
+	if shift.ZoneID == "" {
+		return true
+	}
+
+	zone, ok := s.zoneService.Zones().Zone(shift.ZoneID)
+	if !ok {
+		return false
+	}
+
+	return zone.Contains(geo.Point{
+		Lat: point.Lat,
+		Lng: point.Lng,
+	})
+}
+
+func (s *ShiftService) AddDistance(ctx context.Context, id string, distance float64) error {
+	return s.shiftStorage.AddDistance(ctx, id, distance)
+}
+
+func (s *ShiftService) AddDelivery(ctx context.Context, id string, earnings float64) error {
+	return s.shiftStorage.AddDelivery(ctx, id, earnings)
+}
+
+func (s *ShiftService) AddDeliveryStep(id string, earnings float64) cache.TxStep {
+	return s.shiftStorage.AddDeliveryStep(id, earnings)
+}
+
+// shiftEnd возвращает окончание смены, смена завершенная досрочно заканчивается в момент завершения
+func shiftEnd(shift models.Shift) time.Time {
+	if shift.EndedAt != nil && shift.EndedAt.Before(shift.End) {
+		return *shift.EndedAt
+	}
+
+	return shift.End
+}
+
+// validateShift проверяет, что смена не пустая, а перерывы лежат внутри смены и не пересекаются
+func validateShift(shift models.Shift) error {
+	if shift.Start.IsZero() || !shift.End.After(shift.Start) {
+		return fmt.Errorf("%w: end must be after start", ErrInvalidShift)
+	}
+
+	breaks := make([]models.Break, len(shift.Breaks))
+	copy(breaks, shift.Breaks)
+	sort.Slice(breaks, func(i, j int) bool {
+		return breaks[i].Start.Before(breaks[j].Start)
+	})
+
+	for i := range breaks {
+		if !breaks[i].End.After(breaks[i].Start) {
+			return fmt.Errorf("%w: break %d: end must be after start", ErrInvalidShift, i)
+		}
+
+		if breaks[i].Start.Before(shift.Start) || breaks[i].End.After(shift.End) {
+			return fmt.Errorf("%w: break %d is outside of shift", ErrInvalidShift, i)
+		}
+
+		if i > 0 && breaks[i].Start.Before(breaks[i-1].End) {
+			return fmt.Errorf("%w: breaks overlap", ErrInvalidShift)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/GoGerman/geo-task/clock"
+	"github.com/GoGerman/geo-task/internal/testutil"
+	cmodels "github.com/GoGerman/geo-task/module/courier/models"
+	cservice "github.com/GoGerman/geo-task/module/courier/service"
+	cstorage "github.com/GoGerman/geo-task/module/courier/storage"
+	"github.com/GoGerman/geo-task/module/shift/models"
+	"github.com/GoGerman/geo-task/module/shift/storage"
+	zservice "github.com/GoGerman/geo-task/module/zone/service"
+	zstorage "github.com/GoGerman/geo-task/module/zone/storage"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// zonesFile город вокруг точки по умолчанию и район в его центре
+const zonesFile = `{
+  "type": "FeatureCollection",
+  "features": [
+    {"type": "Feature", "id": "city", "properties": {"name": "city", "allowed": true},
+     "geometry": {"type": "Polygon", "coordinates": [[[30.2, 59.85], [30.5, 59.85], [30.5, 60.0], [30.2, 60.0], [30.2, 59.85]]]}},
+    {"type": "Feature", "id": "district", "properties": {"name": "district", "overlay": true},
+     "geometry": {"type": "Polygon", "coordinates": [[[30.3, 59.9], [30.4, 59.9], [30.4, 59.95], [30.3, 59.95], [30.3, 59.9]]]}}
+  ]
+}`
+
+type testEnv struct {
+	shifts   Shifter
+	couriers cservice.Courierer
+	storage  cstorage.CourierStorager
+	clock    *clock.Manual
+}
+
+func newTestEnv(t *testing.T) testEnv {
+	t.Helper()
+
+	env := testutil.NewEnv(t)
+	path := filepath.Join(t.TempDir(), "zones.geojson")
+	err := os.WriteFile(path, []byte(zonesFile), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zones := zservice.NewZoneService(zstorage.NewZoneStorage(env.Client), env.Clock)
+	err = zones.Init(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	couriers := env.Couriers(testutil.CourierOptions{Zones: zones})
+
+	return testEnv{
+		shifts:   NewShiftService(storage.NewShiftStorage(env.Client), couriers, zones, env.Clock),
+		couriers: couriers,
+		storage:  env.CourierStorage(),
+		clock:    env.Clock,
+	}
+}
+
+// startShift создает курьера и начинает его часовую смену
+func (e testEnv) startShift(t *testing.T) (*cmodels.Courier, *models.Shift) {
+	t.Helper()
+
+	ctx := context.Background()
+	courier, err := e.couriers.CreateCourier(ctx, cmodels.VehicleBicycle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shift, err := e.shifts.CreateShift(ctx, models.Shift{CourierID: courier.ID, End: e.clock.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("CreateShift() error = %v", err)
+	}
+
+	shift, err = e.shifts.StartShift(ctx, courier.ID, shift.ID)
+	if err != nil {
+		t.Fatalf("StartShift() error = %v", err)
+	}
+
+	return courier, shift
+}
+
+// sync приводит курьера id к его смене
+func (e testEnv) sync(t *testing.T, id string) (*cmodels.Courier, *models.Shift, error) {
+	t.Helper()
+
+	courier, err := e.couriers.GetCourier(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return e.shifts.Sync(context.Background(), *courier)
+}
+
+func TestCreateShiftDoesNotChangeCourier(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	_, err := env.shifts.CreateShift(ctx, models.Shift{CourierID: "404", End: env.clock.Now().Add(time.Hour)})
+	if !errors.Is(err, cservice.ErrCourierNotFound) {
+		t.Errorf("CreateShift() error = %v, want %v", err, cservice.ErrCourierNotFound)
+	}
+
+	// курьер вне разрешенной зоны остается на месте, GetCourier перенес бы его в зону
+	outside := cmodels.Courier{ID: "1", Location: cmodels.Point{Lat: 10, Lng: 10}, State: cmodels.CourierOffline}
+	err = env.storage.Save(ctx, outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = env.shifts.CreateShift(ctx, models.Shift{CourierID: outside.ID, End: env.clock.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("CreateShift() error = %v", err)
+	}
+
+	got, err := env.storage.GetByID(ctx, outside.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Location != outside.Location {
+		t.Errorf("Location = %v, want %v", got.Location, outside.Location)
+	}
+}
+
+func TestSyncReleasesCourierWithoutOrdersAfterShift(t *testing.T) {
+	tests := []struct {
+		name   string
+		states []cmodels.CourierState
+	}{
+		{name: "idle"},
+		{name: "assigned", states: []cmodels.CourierState{cmodels.CourierAssigned}},
+		{name: "picking up", states: []cmodels.CourierState{cmodels.CourierAssigned, cmodels.CourierPickingUp}},
+		{name: "delivering", states: []cmodels.CourierState{cmodels.CourierAssigned, cmodels.CourierPickingUp, cmodels.CourierDelivering}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			env := newTestEnv(t)
+			courier, _ := env.startShift(t)
+
+			for _, state := range tt.states {
+				_, err := env.couriers.Transition(ctx, courier.ID, state, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			env.clock.Add(2 * time.Hour)
+
+			got, shift, err := env.sync(t, courier.ID)
+			if err != nil {
+				t.Fatalf("Sync() error = %v", err)
+			}
+			if shift != nil || got.State != cmodels.CourierOffline {
+				t.Errorf("Sync() = %s, shift %v, want offline without shift", got.State, shift)
+			}
+		})
+	}
+}
+
+func TestSyncLetsCourierFinishDeliveries(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	courier, started := env.startShift(t)
+
+	_, err := env.couriers.PickUp(ctx, courier.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env.clock.Add(2 * time.Hour)
+
+	got, shift, err := env.sync(t, courier.ID)
+	if !errors.Is(err, ErrShiftFinished) {
+		t.Fatalf("Sync() error = %v, want %v", err, ErrShiftFinished)
+	}
+	if shift == nil || shift.ID != started.ID || got.State != cmodels.CourierDelivering {
+		t.Errorf("Sync() = %s, shift %v, want delivering on shift %s", got.State, shift, started.ID)
+	}
+
+	// после доставки последнего заказа курьер уходит с линии
+	_, err = env.couriers.DropOff(ctx, courier.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, shift, err = env.sync(t, courier.ID)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if shift != nil || got.State != cmodels.CourierOffline {
+		t.Errorf("Sync() = %s, shift %v, want offline without shift", got.State, shift)
+	}
+}
+
+func TestInZone(t *testing.T) {
+	env := newTestEnv(t)
+
+	inside := cmodels.Point{Lat: 59.93, Lng: 30.36}
+	outside := cmodels.Point{Lat: 59.97, Lng: 30.45}
+
+	tests := []struct {
+		name   string
+		zoneID string
+		point  cmodels.Point
+		want   bool
+	}{
+		{name: "no zone", point: outside, want: true},
+		{name: "inside zone", zoneID: "district", point: inside, want: true},
+		{name: "outside zone", zoneID: "district", point: outside},
+		{name: "deleted zone", zoneID: "removed", point: inside},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := env.shifts.InZone(models.Shift{ZoneID: tt.zoneID}, tt.point); got != tt.want {
+				t.Errorf("InZone(%v) = %v, want %v", tt.point, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConcurrentCreateShiftRejectsOverlap(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+
+	courier, err := env.couriers.CreateCourier(ctx, cmodels.VehicleBicycle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// смены сдвинуты на минуту друг от друга и попарно пересекаются
+	const requests = 10
+	errs := make(chan error, requests)
+
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			start := env.clock.Now().Add(time.Hour + time.Duration(i)*time.Minute)
+			_, err := env.shifts.CreateShift(ctx, models.Shift{CourierID: courier.ID, Start: start, End: start.Add(time.Hour)})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, ErrShiftOverlap):
+			t.Errorf("CreateShift() error = %v, want nil or %v", err, ErrShiftOverlap)
+		}
+	}
+
+	shifts, err := env.shifts.ListShifts(ctx, courier.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 1 || len(shifts) != 1 {
+		t.Errorf("created %d shifts, stored %d, want 1", created, len(shifts))
+	}
+}
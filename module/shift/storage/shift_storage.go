@@ -0,0 +1,263 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/GoGerman/geo-task/cache"
+	"github.com/GoGerman/geo-task/module/shift/models"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+const ShiftIDKey = "shifts:id"
+
+// maxInsertAttempts количество попыток добавить смену, если смены курьера параллельно менялись другим запросом
+const maxInsertAttempts = 100
+
+// ErrInsertConflict смены курьера менялись параллельно во всех попытках добавить смену
+var ErrInsertConflict = errors.New("shift insert conflict")
+
+// CheckFunc проверяет новую смену по сменам курьера, ошибка отменяет добавление
+type CheckFunc func(shifts []models.Shift) error
+
+// поля hash смены: описание смены в json и счетчики итогов смены
+const (
+	fieldData       = "data"
+	fieldDistance   = "distance"
+	fieldDeliveries = "deliveries"
+	fieldEarnings   = "earnings"
+)
+
+type ShiftStorager interface {
+	Save(ctx context.Context, shift models.Shift) error                             // сохранить смену и добавить ее в список смен курьера
+	Insert(ctx context.Context, shift models.Shift, check CheckFunc) error          // атомарно проверить смены курьера функцией check и добавить новую смену
+	GetByID(ctx context.Context, id string) (*models.Shift, error)                  // получить смену по id вместе с итогами, nil если смены нет
+	List(ctx context.Context, courierID string) ([]models.Shift, error)             // получить смены курьера в порядке планового начала
+	Last(ctx context.Context, courierID string, t time.Time) (*models.Shift, error) // получить последнюю смену курьера, начинающуюся не позже t
+	AddDistance(ctx context.Context, id string, distance float64) error             // добавить пройденное расстояние в итоги смены
+	AddDelivery(ctx context.Context, id string, earnings float64) error             // добавить доставленный заказ в итоги смены
+	AddDeliveryStep(id string, earnings float64) cache.TxStep                       // шаг транзакции, добавляющий доставленный заказ в итоги смены
+	GenerateUniqueID(ctx context.Context) (int64, error)                            // сгенерировать уникальный id
+}
+
+type ShiftStorage struct {
+	storage *redis.Client
+}
+
+func NewShiftStorage(storage *redis.Client) ShiftStorager {
+	return &ShiftStorage{storage: storage}
+}
+
+// ShiftKey возвращает ключ hash, в котором хранится смена
+func ShiftKey(id string) string {
+	return "shift:" + id
+}
+
+// CourierShiftsKey возвращает ключ sorted set со сменами курьера, score - плановое начало смены
+func CourierShiftsKey(courierID string) string {
+	return "courier:" + courierID + ":shifts"
+}
+
+func (s *ShiftStorage) Save(ctx context.Context, shift models.Shift) error {
+	// итоги хранятся отдельными счетчиками и не перезаписываются при сохранении смены
+	shift.Report = models.ShiftReport{}
+
+	_, err := s.storage.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		queueSave(ctx, pipe, shift)
+		return nil
+	})
+
+	return err
+}
+
+// Insert список смен курьера отслеживается через WATCH, поэтому из двух пересекающихся смен,
+// которые добавляются параллельно, вторая проверяется уже вместе с первой
+func (s *ShiftStorage) Insert(ctx context.Context, shift models.Shift, check CheckFunc) error {
+	shift.Report = models.ShiftReport{}
+
+	for i := 0; i < maxInsertAttempts; i++ {
+		err := s.storage.Watch(ctx, func(tx *redis.Tx) error {
+			shifts, err := list(ctx, tx, shift.CourierID)
+			if err != nil {
+				return err
+			}
+
+			err = check(shifts)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				queueSave(ctx, pipe, shift)
+				return nil
+			})
+
+			return err
+		}, CourierShiftsKey(shift.CourierID))
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+
+		return err
+	}
+
+	return ErrInsertConflict
+}
+
+// queueSave добавляет в MULTI сохранение смены и ее добавление в список смен курьера
+func queueSave(ctx context.Context, pipe redis.Pipeliner, shift models.Shift) {
+	pipe.HSet(ctx, ShiftKey(shift.ID), fieldData, shift)
+	pipe.ZAdd(ctx, CourierShiftsKey(shift.CourierID), redis.Z{
+		Score:  float64(shift.Start.Unix()),
+		Member: shift.ID,
+	})
+}
+
+func (s *ShiftStorage) GetByID(ctx context.Context, id string) (*models.Shift, error) {
+	values, err := s.storage.HGetAll(ctx, ShiftKey(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeShift(values)
+}
+
+func (s *ShiftStorage) List(ctx context.Context, courierID string) ([]models.Shift, error) {
+	return list(ctx, s.storage, courierID)
+}
+
+// list получает смены курьера через client, которым может быть и транзакция с WATCH
+func list(ctx context.Context, client redis.Cmdable, courierID string) ([]models.Shift, error) {
+	ids, err := client.ZRange(ctx, CourierShiftsKey(courierID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return getByIDs(ctx, client, ids)
+}
+
+func (s *ShiftStorage) Last(ctx context.Context, courierID string, t time.Time) (*models.Shift, error) {
+	ids, err := s.storage.ZRevRangeByScore(ctx, CourierShiftsKey(courierID), &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(t.Unix(), 10),
+		Count: 1,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	return s.GetByID(ctx, ids[0])
+}
+
+// getByIDs получает смены по списку id с сохранением порядка, отсутствующие смены пропускаются
+func getByIDs(ctx context.Context, client redis.Cmdable, ids []string) ([]models.Shift, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	cmds := make([]*redis.MapStringStringCmd, 0, len(ids))
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i := range ids {
+			cmds = append(cmds, pipe.HGetAll(ctx, ShiftKey(ids[i])))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	shifts := make([]models.Shift, 0, len(cmds))
+	for i := range cmds {
+		shift, err := decodeShift(cmds[i].Val())
+		if err != nil {
+			return nil, err
+		}
+
+		if shift == nil {
+			continue
+		}
+
+		shifts = append(shifts, *shift)
+	}
+
+	return shifts, nil
+}
+
+func (s *ShiftStorage) AddDistance(ctx context.Context, id string, distance float64) error {
+	return s.storage.HIncrByFloat(ctx, ShiftKey(id), fieldDistance, distance).Err()
+}
+
+func (s *ShiftStorage) AddDelivery(ctx context.Context, id string, earnings float64) error {
+	step := s.AddDeliveryStep(id, earnings)
+
+	_, err := s.storage.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		step.Queue(ctx, pipe)
+		return nil
+	})
+
+	return err
+}
+
+func (s *ShiftStorage) AddDeliveryStep(id string, earnings float64) cache.TxStep {
+	return cache.QueueStep(func(ctx context.Context, pipe redis.Pipeliner) {
+		pipe.HIncrBy(ctx, ShiftKey(id), fieldDeliveries, 1)
+		pipe.HIncrByFloat(ctx, ShiftKey(id), fieldEarnings, earnings)
+	})
+}
+
+func (s *ShiftStorage) GenerateUniqueID(ctx context.Context) (int64, error) {
+	return s.storage.Incr(ctx, ShiftIDKey).Result()
+}
+
+// decodeShift собирает смену из полей hash, nil если смены нет
+func decodeShift(values map[string]string) (*models.Shift, error) {
+	data, ok := values[fieldData]
+	if !ok {
+		return nil, nil
+	}
+
+	var shift models.Shift
+	err := json.Unmarshal([]byte(data), &shift)
+	if err != nil {
+		return nil, err
+	}
+
+	// счетчики появляются только после первого перемещения или доставки
+	shift.Report.Distance, err = parseFloat(values[fieldDistance])
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := parseFloat(values[fieldDeliveries])
+	if err != nil {
+		return nil, err
+	}
+	shift.Report.Deliveries = int(deliveries)
+
+	shift.Report.Earnings, err = parseFloat(values[fieldEarnings])
+	if err != nil {
+		return nil, err
+	}
+
+	return &shift, nil
+}
+
+func parseFloat(v string) (float64, error) {
+	if v == "" {
+		return 0, nil
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid shift counter %q: %w", v, err)
+	}
+
+	return f, nil
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"errors"
+	"github.com/GoGerman/geo-task/module/zone/models"
+	"github.com/GoGerman/geo-task/module/zone/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+type ZoneController struct {
+	zoneService service.Zoner
+}
+
+func NewZoneController(zoneService service.Zoner) *ZoneController {
+	return &ZoneController{zoneService: zoneService}
+}
+
+func (z *ZoneController) List(ctx *gin.Context) {
+	zones, err := z.zoneService.List(ctx)
+	if err != nil {
+		z.error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, zones)
+}
+
+func (z *ZoneController) Get(ctx *gin.Context) {
+	zone, err := z.zoneService.GetByID(ctx, ctx.Param("id"))
+	if err != nil {
+		z.error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, zone)
+}
+
+func (z *ZoneController) Create(ctx *gin.Context) {
+	var zone models.Zone
+
+	err := ctx.ShouldBindJSON(&zone)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := z.zoneService.Create(ctx, zone)
+	if err != nil {
+		z.error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, created)
+}
+
+func (z *ZoneController) Update(ctx *gin.Context) {
+	var zone models.Zone
+
+	err := ctx.ShouldBindJSON(&zone)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// id зоны берется из пути, а не из тела запроса
+	zone.ID = ctx.Param("id")
+
+	updated, err := z.zoneService.Update(ctx, zone)
+	if err != nil {
+		z.error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updated)
+}
+
+func (z *ZoneController) Delete(ctx *gin.Context) {
+	err := z.zoneService.Delete(ctx, ctx.Param("id"))
+	if err != nil {
+		z.error(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// GeoJSON отдает зоны, с которыми сейчас работают сервисы, в формате GeoJSON.
+// Поддерживается кеширование через ETag и If-None-Match
+func (z *ZoneController) GeoJSON(ctx *gin.Context) {
+	data, etag, err := z.zoneService.Zones().GeoJSON()
+	if err != nil {
+		z.error(ctx, err)
+		return
+	}
+	etag = `"` + etag + `"`
+
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", "no-cache")
+
+	if etagMatches(ctx.GetHeader("If-None-Match"), etag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	ctx.Data(http.StatusOK, "application/geo+json", data)
+}
+
+func (z *ZoneController) error(ctx *gin.Context, err error) {
+	var invalid *service.InvalidZonesError
+
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrZoneNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrZoneExists):
+		status = http.StatusConflict
+	case errors.As(err, &invalid):
+		status = http.StatusBadRequest
+	}
+
+	ctx.JSON(status, gin.H{"error": err.Error()})
+}
+
+// etagMatches проверяет заголовок If-None-Match, который может содержать список тегов или слабые теги
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+
+	return false
+}
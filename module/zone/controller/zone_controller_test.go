@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"github.com/GoGerman/geo-task/clock"
+	"github.com/GoGerman/geo-task/geo"
+	"github.com/GoGerman/geo-task/module/zone/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var (
+	cityZone = geo.ZoneSpec{ID: "city", Allowed: true, Geometry: geo.Geometry{Type: geo.GeometryPolygon, Coordinates: []byte(`[[[0,0],[10,0],[10,10],[0,10],[0,0]]]`)}}
+	parkZone = geo.ZoneSpec{ID: "park", Geometry: geo.Geometry{Type: geo.GeometryPolygon, Coordinates: []byte(`[[[4,4],[6,4],[6,6],[4,6],[4,4]]]`)}}
+)
+
+// storeZones сервис зон, который отдает только текущий набор зон
+type storeZones struct {
+	service.Zoner
+	store *geo.ZoneStore
+}
+
+func (s storeZones) Zones() *geo.ZoneSet {
+	return s.store.Zones()
+}
+
+func newZoneSet(t *testing.T, specs ...geo.ZoneSpec) *geo.ZoneSet {
+	t.Helper()
+
+	set, err := geo.NewZoneSet(specs, clock.NewManual(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return set
+}
+
+func newTestRouter(t *testing.T, store *geo.ZoneStore) *gin.Engine {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/zones.geojson", NewZoneController(storeZones{store: store}).GeoJSON)
+
+	return router
+}
+
+func getGeoJSON(router *gin.Engine, ifNoneMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/zones.geojson", nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestGeoJSONNotModified(t *testing.T) {
+	store := geo.NewZoneStore(newZoneSet(t, cityZone, parkZone))
+	router := newTestRouter(t, store)
+
+	rec := getGeoJSON(router, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	etag := rec.Header().Get("ETag")
+	if len(etag) < 3 || etag[0] != '"' || etag[len(etag)-1] != '"' {
+		t.Fatalf("ETag = %q, want quoted tag", etag)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/geo+json" {
+		t.Errorf("Content-Type = %q, want application/geo+json", got)
+	}
+	if rec.Body.Len() == 0 {
+		t.Error("body is empty")
+	}
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		want        int
+	}{
+		{name: "same etag", ifNoneMatch: etag, want: http.StatusNotModified},
+		{name: "weak etag", ifNoneMatch: "W/" + etag, want: http.StatusNotModified},
+		{name: "etag in list", ifNoneMatch: `"other", ` + etag, want: http.StatusNotModified},
+		{name: "any", ifNoneMatch: "*", want: http.StatusNotModified},
+		{name: "other etag", ifNoneMatch: `"other"`, want: http.StatusOK},
+		{name: "unquoted etag", ifNoneMatch: etag[1 : len(etag)-1], want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := getGeoJSON(router, tt.ifNoneMatch)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if got := rec.Header().Get("ETag"); got != etag {
+				t.Errorf("ETag = %q, want %q", got, etag)
+			}
+			if tt.want == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("304 body = %q, want empty", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestGeoJSONModifiedAfterZonesChange(t *testing.T) {
+	store := geo.NewZoneStore(newZoneSet(t, cityZone, parkZone))
+	router := newTestRouter(t, store)
+
+	etag := getGeoJSON(router, "").Header().Get("ETag")
+
+	store.Swap(newZoneSet(t, cityZone))
+
+	rec := getGeoJSON(router, etag)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status after zones change = %d, want %d", rec.Code, http.StatusOK)
+	}
+	changed := rec.Header().Get("ETag")
+	if changed == etag {
+		t.Errorf("ETag after zones change = %q, want new tag", changed)
+	}
+
+	if rec := getGeoJSON(router, changed); rec.Code != http.StatusNotModified {
+		t.Errorf("status with new ETag = %d, want %d", rec.Code, http.StatusNotModified)
+	}
+}
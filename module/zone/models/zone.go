@@ -0,0 +1,37 @@
+package models
+
+import "github.com/GoGerman/geo-task/geo"
+
+type Zone struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	Allowed    bool           `json:"allowed"`
+	Geometry   geo.Geometry   `json:"geometry"`             // GeoJSON геометрия Polygon или MultiPolygon
+	Schedule   *geo.Schedule  `json:"schedule,omitempty"`   // расписание действия запрещенной зоны, без него зона действует всегда
+	Overlay    bool           `json:"overlay,omitempty"`    // зона только задает атрибуты и не ограничивает перемещение
+	Attributes geo.Attributes `json:"attributes,omitempty"` // числовые атрибуты зоны: price_multiplier, min_delivery_price, max_delivery_price, max_orders
+}
+
+func (z Zone) Spec() geo.ZoneSpec {
+	return geo.ZoneSpec{
+		ID:         z.ID,
+		Name:       z.Name,
+		Allowed:    z.Allowed,
+		Geometry:   z.Geometry,
+		Schedule:   z.Schedule,
+		Overlay:    z.Overlay,
+		Attributes: z.Attributes,
+	}
+}
+
+func NewZoneFromSpec(spec geo.ZoneSpec) Zone {
+	return Zone{
+		ID:         spec.ID,
+		Name:       spec.Name,
+		Allowed:    spec.Allowed,
+		Geometry:   spec.Geometry,
+		Schedule:   spec.Schedule,
+		Overlay:    spec.Overlay,
+		Attributes: spec.Attributes,
+	}
+}
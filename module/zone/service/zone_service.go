@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/GoGerman/geo-task/clock"
+	"github.com/GoGerman/geo-task/geo"
+	"github.com/GoGerman/geo-task/module/zone/models"
+	"github.com/GoGerman/geo-task/module/zone/storage"
+	"log"
+	"strconv"
+	"sync"
+)
+
+var (
+	ErrZoneNotFound = errors.New("zone not found")
+	ErrZoneExists   = errors.New("zone already exists")
+)
+
+// InvalidZonesError набор зон после изменения не прошел проверку, изменение не сохранено
+type InvalidZonesError struct {
+	Err error
+}
+
+func (e *InvalidZonesError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidZonesError) Unwrap() error {
+	return e.Err
+}
+
+type Zoner interface {
+	geo.ZoneProvider
+	List(ctx context.Context) ([]models.Zone, error)                    // возвращает все зоны
+	GetByID(ctx context.Context, id string) (*models.Zone, error)       // возвращает зону по id
+	Create(ctx context.Context, zone models.Zone) (*models.Zone, error) // создает зону, если id не задан - генерирует его
+	Update(ctx context.Context, zone models.Zone) (*models.Zone, error) // обновляет существующую зону
+	Delete(ctx context.Context, id string) error                        // удаляет зону
+	Reload(ctx context.Context) error                                   // перечитывает зоны из хранилища и подменяет текущий набор
+}
+
+// ZoneService управляет зонами в хранилище и держит актуальный набор зон для сервисов курьеров и заказов.
+// Каждое изменение проверяется построением нового набора зон до сохранения
+type ZoneService struct {
+	storage storage.ZoneStorager
+	store   geo.ZoneStore
+	clock   clock.Clock
+	mu      sync.Mutex // изменения и перезагрузка зон на одном инстансе выполняются последовательно
+}
+
+func NewZoneService(storage storage.ZoneStorager, clock clock.Clock) *ZoneService {
+	return &ZoneService{storage: storage, clock: clock}
+}
+
+// Init загружает зоны из хранилища, если хранилище пусто - заполняет его зонами из GeoJSON файла
+func (z *ZoneService) Init(ctx context.Context, zonesFile string) error {
+	zones, err := z.storage.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(zones) == 0 {
+		specs, err := geo.LoadZoneSpecs(zonesFile)
+		if err != nil {
+			return err
+		}
+
+		zones = make([]models.Zone, 0, len(specs))
+		for i := range specs {
+			zones = append(zones, models.NewZoneFromSpec(specs[i]))
+		}
+
+		err = z.storage.Save(ctx, zones...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return z.swap(zones)
+}
+
+// Zones возвращает текущий набор зон без блокировок
+func (z *ZoneService) Zones() *geo.ZoneSet {
+	return z.store.Zones()
+}
+
+func (z *ZoneService) List(ctx context.Context) ([]models.Zone, error) {
+	return z.storage.List(ctx)
+}
+
+func (z *ZoneService) GetByID(ctx context.Context, id string) (*models.Zone, error) {
+	zone, err := z.storage.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if zone == nil {
+		return nil, ErrZoneNotFound
+	}
+
+	return zone, nil
+}
+
+func (z *ZoneService) Create(ctx context.Context, zone models.Zone) (*models.Zone, error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	created := zone
+	err := z.apply(ctx, func(zones []models.Zone) ([]models.Zone, *storage.Change, error) {
+		created = zone
+		if created.ID == "" {
+			id, err := z.storage.GenerateUniqueID(ctx, maxNumericID(zones))
+			if err != nil {
+				return nil, nil, err
+			}
+			created.ID = strconv.FormatInt(id, 10)
+		}
+
+		for i := range zones {
+			if zones[i].ID == created.ID {
+				return nil, nil, ErrZoneExists
+			}
+		}
+
+		return append(zones, created), &storage.Change{Save: []models.Zone{created}}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (z *ZoneService) Update(ctx context.Context, zone models.Zone) (*models.Zone, error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	err := z.apply(ctx, func(zones []models.Zone) ([]models.Zone, *storage.Change, error) {
+		found := false
+		for i := range zones {
+			if zones[i].ID == zone.ID {
+				zones[i] = zone
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, nil, ErrZoneNotFound
+		}
+
+		return zones, &storage.Change{Save: []models.Zone{zone}}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &zone, nil
+}
+
+func (z *ZoneService) Delete(ctx context.Context, id string) error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	return z.apply(ctx, func(zones []models.Zone) ([]models.Zone, *storage.Change, error) {
+		rest := make([]models.Zone, 0, len(zones))
+		for i := range zones {
+			if zones[i].ID != id {
+				rest = append(rest, zones[i])
+			}
+		}
+
+		if len(rest) == len(zones) {
+			return nil, nil, ErrZoneNotFound
+		}
+
+		return rest, &storage.Change{Delete: []string{id}}, nil
+	})
+}
+
+// Reload выполняется под той же блокировкой, что и изменения, иначе набор, прочитанный до изменения,
+// мог бы подменить набор после него
+func (z *ZoneService) Reload(ctx context.Context) error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	zones, err := z.storage.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	return z.swap(zones)
+}
+
+// changeFunc получает все зоны и возвращает итоговый набор зон и изменение, которое к нему приводит
+type changeFunc func(zones []models.Zone) ([]models.Zone, *storage.Change, error)
+
+// apply проверяет итоговый набор зон, сохраняет изменение и оповещает остальные инстансы.
+// Зоны читаются, проверяются и сохраняются в одной транзакции хранилища, поэтому изменение,
+// проверенное по набору, который успел поменять другой инстанс, повторяется на свежем наборе
+func (z *ZoneService) apply(ctx context.Context, change changeFunc) error {
+	var set *geo.ZoneSet
+
+	err := z.storage.Update(ctx, func(zones []models.Zone) (*storage.Change, error) {
+		next, ch, err := change(zones)
+		if err != nil {
+			return nil, err
+		}
+
+		set, err = z.build(next)
+		if err != nil {
+			return nil, &InvalidZonesError{Err: err}
+		}
+
+		return ch, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	z.store.Swap(set)
+
+	err = z.storage.NotifyUpdated(ctx)
+	if err != nil {
+		log.Printf("error while notifying zones update: %v", err)
+	}
+
+	return nil
+}
+
+func (z *ZoneService) swap(zones []models.Zone) error {
+	set, err := z.build(zones)
+	if err != nil {
+		return err
+	}
+
+	z.store.Swap(set)
+
+	return nil
+}
+
+// maxNumericID возвращает наибольший из числовых id зон, зоны из GeoJSON файла могут иметь числовые id
+func maxNumericID(zones []models.Zone) int64 {
+	var max int64
+	for i := range zones {
+		id, err := strconv.ParseInt(zones[i].ID, 10, 64)
+		if err == nil && id > max {
+			max = id
+		}
+	}
+
+	return max
+}
+
+func (z *ZoneService) build(zones []models.Zone) (*geo.ZoneSet, error) {
+	specs := make([]geo.ZoneSpec, 0, len(zones))
+	for i := range zones {
+		specs = append(specs, zones[i].Spec())
+	}
+
+	return geo.NewZoneSet(specs, z.clock)
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/GoGerman/geo-task/clock"
+	"github.com/GoGerman/geo-task/geo"
+	"github.com/GoGerman/geo-task/module/zone/models"
+	"github.com/GoGerman/geo-task/module/zone/storage"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// zonesFile зоны с числовыми id, как в GeoJSON файле, где id задан числом
+const zonesFile = `{
+  "type": "FeatureCollection",
+  "features": [
+    {"type": "Feature", "id": 1, "properties": {"name": "city", "allowed": true},
+     "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [10, 0], [10, 10], [0, 10], [0, 0]]]}},
+    {"type": "Feature", "id": 2, "properties": {"name": "park", "allowed": false},
+     "geometry": {"type": "Polygon", "coordinates": [[[4, 4], [6, 4], [6, 6], [4, 6], [4, 4]]]}}
+  ]
+}`
+
+func newTestZoneService(t *testing.T) *ZoneService {
+	t.Helper()
+
+	return newTestZoneServiceWithClock(t, clock.Real{})
+}
+
+// newTestZoneServiceWithClock создает сервис зон, расписания которого проверяются по часам clk
+func newTestZoneServiceWithClock(t *testing.T, clk clock.Clock) *ZoneService {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	rclient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rclient.Close() })
+
+	path := filepath.Join(t.TempDir(), "zones.geojson")
+	err := os.WriteFile(path, []byte(zonesFile), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	z := NewZoneService(storage.NewZoneStorage(rclient), clk)
+	err = z.Init(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	return z
+}
+
+func disabledZone(lng, lat float64) models.Zone {
+	return models.Zone{
+		Name: "closed street",
+		Geometry: geo.Geometry{
+			Type:        geo.GeometryPolygon,
+			Coordinates: []byte(`[[[` + ftoa(lng) + `,` + ftoa(lat) + `],[` + ftoa(lng+1) + `,` + ftoa(lat) + `],[` + ftoa(lng+1) + `,` + ftoa(lat+1) + `],[` + ftoa(lng) + `,` + ftoa(lat+1) + `],[` + ftoa(lng) + `,` + ftoa(lat) + `]]]`),
+		},
+	}
+}
+
+func ftoa(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func TestCreateSkipsSeededNumericIDs(t *testing.T) {
+	ctx := context.Background()
+	z := newTestZoneService(t)
+
+	created, err := z.Create(ctx, disabledZone(1, 1))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.ID != "3" {
+		t.Errorf("Create() id = %q, want %q", created.ID, "3")
+	}
+
+	// зона с явным числовым id сдвигает счетчик для следующих зон
+	explicit := disabledZone(7, 7)
+	explicit.ID = "10"
+	_, err = z.Create(ctx, explicit)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	created, err = z.Create(ctx, disabledZone(1, 7))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.ID != "11" {
+		t.Errorf("Create() id = %q, want %q", created.ID, "11")
+	}
+
+	_, err = z.Create(ctx, explicit)
+	if !errors.Is(err, ErrZoneExists) {
+		t.Errorf("Create() error = %v, want %v", err, ErrZoneExists)
+	}
+}
+
+// interleavedStorage хранилище, в котором другой инстанс успевает изменить зоны перед первой попыткой изменения
+type interleavedStorage struct {
+	storage.ZoneStorager
+	before func()
+}
+
+func (s *interleavedStorage) Update(ctx context.Context, fn storage.UpdateFunc) error {
+	return s.ZoneStorager.Update(ctx, func(zones []models.Zone) (*storage.Change, error) {
+		if before := s.before; before != nil {
+			s.before = nil
+			before()
+		}
+
+		return fn(zones)
+	})
+}
+
+func TestDeleteRechecksZonesChangedByAnotherInstance(t *testing.T) {
+	ctx := context.Background()
+	z := newTestZoneService(t)
+
+	// вторая разрешенная зона покрывает запрещенный парк, поэтому любая из двух зон может остаться одна
+	second := models.Zone{ID: "3", Name: "center", Allowed: true, Geometry: geo.Geometry{
+		Type:        geo.GeometryPolygon,
+		Coordinates: []byte(`[[[3,3],[7,3],[7,7],[3,7],[3,3]]]`),
+	}}
+	_, err := z.Create(ctx, second)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// другой инстанс удаляет вторую разрешенную зону, пока первый проверяет удаление первой
+	other := NewZoneService(z.storage, clock.Real{})
+	z.storage = &interleavedStorage{ZoneStorager: z.storage, before: func() {
+		err := other.Delete(ctx, "3")
+		if err != nil {
+			t.Errorf("Delete() on other instance error = %v", err)
+		}
+	}}
+
+	err = z.Delete(ctx, "1")
+	if !errors.Is(err, geo.ErrNoAllowedZone) {
+		t.Fatalf("Delete() error = %v, want %v", err, geo.ErrNoAllowedZone)
+	}
+
+	zone, err := z.GetByID(ctx, "1")
+	if err != nil || zone == nil {
+		t.Errorf("GetByID() = %v, %v, want zone kept", zone, err)
+	}
+}
+
+func TestReloadDoesNotOverrideConcurrentChange(t *testing.T) {
+	ctx := context.Background()
+	z := newTestZoneService(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			_, err := z.Create(ctx, disabledZone(1, float64(i)*0.1))
+			if err != nil {
+				t.Errorf("Create() error = %v", err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			err := z.Reload(ctx)
+			if err != nil {
+				t.Errorf("Reload() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// после всех изменений текущий набор содержит каждую созданную зону
+	zones, err := z.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range zones {
+		if _, ok := z.Zones().Zone(zones[i].ID); !ok {
+			t.Errorf("zone %s is stored but missing from the current set", zones[i].ID)
+		}
+	}
+}
+
+// geoJSON возвращает текущий набор зон в GeoJSON и его ETag
+func geoJSON(t *testing.T, z *ZoneService) ([]byte, string) {
+	t.Helper()
+
+	data, etag, err := z.Zones().GeoJSON()
+	if err != nil {
+		t.Fatalf("GeoJSON() error = %v", err)
+	}
+	if etag == "" {
+		t.Fatal("GeoJSON() etag is empty")
+	}
+
+	return data, etag
+}
+
+func TestGeoJSONETagChangesWithZones(t *testing.T) {
+	ctx := context.Background()
+	z := newTestZoneService(t)
+
+	data, etag := geoJSON(t, z)
+
+	// тот же набор зон, в том числе перечитанный из хранилища, отдается с тем же ETag
+	err := z.Reload(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, sameETag := geoJSON(t, z)
+	if sameETag != etag || !bytes.Equal(again, data) {
+		t.Errorf("after Reload() etag = %s, want %s", sameETag, etag)
+	}
+
+	created, err := z.Create(ctx, disabledZone(1, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, changedETag := geoJSON(t, z)
+	if changedETag == etag {
+		t.Errorf("after Create() etag = %s, want new etag", changedETag)
+	}
+	if !bytes.Contains(changed, []byte(`"id":"`+created.ID+`"`)) {
+		t.Errorf("after Create() GeoJSON does not contain zone %s: %s", created.ID, changed)
+	}
+
+	err = z.Delete(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, deletedETag := geoJSON(t, z); deletedETag != etag {
+		t.Errorf("after Delete() etag = %s, want original %s", deletedETag, etag)
+	}
+}
+
+func TestGeoJSONETagChangesWithSchedule(t *testing.T) {
+	// 2024-06-15 12:00 UTC, зона закрыта с 10:00 до 18:00
+	clk := clock.NewManual(time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC))
+	z := newTestZoneServiceWithClock(t, clk)
+
+	zone := disabledZone(1, 1)
+	zone.Schedule = &geo.Schedule{Windows: []geo.ScheduleWindow{{Start: "10:00", End: "18:00"}}}
+	_, err := z.Create(context.Background(), zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active, activeETag := geoJSON(t, z)
+	if !bytes.Contains(active, []byte(`"active":true`)) {
+		t.Errorf("GeoJSON inside schedule window = %s, want active zone", active)
+	}
+
+	clk.Add(7 * time.Hour)
+	inactive, inactiveETag := geoJSON(t, z)
+	if inactiveETag == activeETag {
+		t.Errorf("etag outside schedule window = %s, want new etag", inactiveETag)
+	}
+	if !bytes.Contains(inactive, []byte(`"active":false`)) {
+		t.Errorf("GeoJSON outside schedule window = %s, want inactive zone", inactive)
+	}
+
+	// на следующий день окно снова открыто, и клиенту с прежним ETag подходит его копия
+	clk.Add(17 * time.Hour)
+	if _, etag := geoJSON(t, z); etag != activeETag {
+		t.Errorf("etag inside next schedule window = %s, want %s", etag, activeETag)
+	}
+}
@@ -0,0 +1,255 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/GoGerman/geo-task/module/zone/models"
+	"github.com/redis/go-redis/v9"
+	"sort"
+)
+
+const ZoneIDKey = "zone:id"
+const ZonesKey = "zones"
+const ZonesUpdatedChannel = "zones:updated"
+
+// ZonesVersionKey счетчик изменений зон, каждое изменение hash зон увеличивает его в той же транзакции
+const ZonesVersionKey = "zones:version"
+
+// maxUpdateAttempts количество попыток изменить зоны, если они параллельно менялись другим запросом или инстансом
+const maxUpdateAttempts = 100
+
+// ErrUpdateConflict зоны менялись параллельно во всех попытках изменения
+var ErrUpdateConflict = errors.New("zones update conflict")
+
+// Change изменение зон: сохраняемые зоны и id удаляемых зон
+type Change struct {
+	Save   []models.Zone
+	Delete []string
+}
+
+// UpdateFunc получает все зоны и возвращает изменение, ошибка отменяет изменение
+type UpdateFunc func(zones []models.Zone) (*Change, error)
+
+type ZoneStorager interface {
+	List(ctx context.Context) ([]models.Zone, error)                  // получить все зоны
+	GetByID(ctx context.Context, id string) (*models.Zone, error)     // получить зону по id
+	Save(ctx context.Context, zones ...models.Zone) error             // сохранить зоны
+	Delete(ctx context.Context, id string) (bool, error)              // удалить зону, false если зоны не было
+	Update(ctx context.Context, fn UpdateFunc) error                  // атомарно изменить зоны функцией fn, изменение сохраняется, только если зоны не менялись после чтения
+	GenerateUniqueID(ctx context.Context, after int64) (int64, error) // сгенерировать уникальный id больше after
+	NotifyUpdated(ctx context.Context) error                          // оповестить все инстансы об изменении зон
+	SubscribeUpdated(ctx context.Context) (<-chan struct{}, error)    // подписаться на изменения зон
+}
+
+// generateIDScript увеличивает счетчик id и, если он не больше ARGV[1], переносит его за ARGV[1].
+// Так сгенерированный id не совпадает с числовыми id, заданными в GeoJSON файле или при создании зоны.
+// KEYS[1] - счетчик id, ARGV[1] - наибольший занятый числовой id
+var generateIDScript = redis.NewScript(`
+local id = redis.call('INCR', KEYS[1])
+local after = tonumber(ARGV[1])
+if id <= after then
+	id = after + 1
+	redis.call('SET', KEYS[1], id)
+end
+return id
+`)
+
+type ZoneStorage struct {
+	storage *redis.Client
+}
+
+func NewZoneStorage(storage *redis.Client) ZoneStorager {
+	return &ZoneStorage{storage: storage}
+}
+
+func (z *ZoneStorage) List(ctx context.Context) ([]models.Zone, error) {
+	// зоны хранятся в hash zones, где поле - id зоны, значение - json
+	data, err := z.storage.HGetAll(ctx, ZonesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeZones(data)
+}
+
+// decodeZones собирает зоны из полей hash зон в порядке id
+func decodeZones(data map[string]string) ([]models.Zone, error) {
+	zones := make([]models.Zone, 0, len(data))
+	for _, v := range data {
+		var zone models.Zone
+		err := json.Unmarshal([]byte(v), &zone)
+		if err != nil {
+			return nil, err
+		}
+
+		zones = append(zones, zone)
+	}
+
+	// порядок полей hash не определен, сортируем для стабильного результата
+	sort.Slice(zones, func(i, j int) bool {
+		return zones[i].ID < zones[j].ID
+	})
+
+	return zones, nil
+}
+
+func (z *ZoneStorage) GetByID(ctx context.Context, id string) (*models.Zone, error) {
+	var zone models.Zone
+
+	data, err := z.storage.HGet(ctx, ZonesKey, id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &zone)
+	if err != nil {
+		return nil, err
+	}
+
+	return &zone, nil
+}
+
+func (z *ZoneStorage) Save(ctx context.Context, zones ...models.Zone) error {
+	if len(zones) == 0 {
+		return nil
+	}
+
+	values, err := encodeZones(zones)
+	if err != nil {
+		return err
+	}
+
+	_, err = z.storage.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, ZonesKey, values...)
+		pipe.Incr(ctx, ZonesVersionKey)
+		return nil
+	})
+
+	return err
+}
+
+// encodeZones возвращает пары id и json зон для HSET
+func encodeZones(zones []models.Zone) ([]interface{}, error) {
+	values := make([]interface{}, 0, len(zones)*2)
+	for i := range zones {
+		data, err := json.Marshal(zones[i])
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, zones[i].ID, data)
+	}
+
+	return values, nil
+}
+
+// Update hash зон и версия зон отслеживаются через WATCH, поэтому изменение, проверенное по устаревшему набору зон,
+// не сохраняется, а повторяется на свежем наборе
+func (z *ZoneStorage) Update(ctx context.Context, fn UpdateFunc) error {
+	for i := 0; i < maxUpdateAttempts; i++ {
+		err := z.storage.Watch(ctx, func(tx *redis.Tx) error {
+			data, err := tx.HGetAll(ctx, ZonesKey).Result()
+			if err != nil {
+				return err
+			}
+
+			zones, err := decodeZones(data)
+			if err != nil {
+				return err
+			}
+
+			change, err := fn(zones)
+			if err != nil {
+				return err
+			}
+
+			values, err := encodeZones(change.Save)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				if len(values) > 0 {
+					pipe.HSet(ctx, ZonesKey, values...)
+				}
+				if len(change.Delete) > 0 {
+					pipe.HDel(ctx, ZonesKey, change.Delete...)
+				}
+				pipe.Incr(ctx, ZonesVersionKey)
+				return nil
+			})
+
+			return err
+		}, ZonesKey, ZonesVersionKey)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+
+		return err
+	}
+
+	return ErrUpdateConflict
+}
+
+func (z *ZoneStorage) Delete(ctx context.Context, id string) (bool, error) {
+	var n *redis.IntCmd
+
+	_, err := z.storage.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		n = pipe.HDel(ctx, ZonesKey, id)
+		pipe.Incr(ctx, ZonesVersionKey)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return n.Val() > 0, nil
+}
+
+func (z *ZoneStorage) GenerateUniqueID(ctx context.Context, after int64) (int64, error) {
+	return generateIDScript.Run(ctx, z.storage, []string{ZoneIDKey}, after).Int64()
+}
+
+func (z *ZoneStorage) NotifyUpdated(ctx context.Context) error {
+	return z.storage.Publish(ctx, ZonesUpdatedChannel, "").Err()
+}
+
+func (z *ZoneStorage) SubscribeUpdated(ctx context.Context) (<-chan struct{}, error) {
+	pubsub := z.storage.Subscribe(ctx, ZonesUpdatedChannel)
+
+	// дожидаемся подтверждения подписки, чтобы не пропустить ошибку соединения
+	_, err := pubsub.Receive(ctx)
+	if err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	updates := make(chan struct{}, 1)
+	go func() {
+		defer pubsub.Close()
+		defer close(updates)
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-messages:
+				if !ok {
+					return
+				}
+				// несколько изменений подряд схлопываются в одну перезагрузку
+				select {
+				case updates <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return updates, nil
+}
@@ -0,0 +1,47 @@
+package random
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sync"
+)
+
+// Rand источник псевдослучайных чисел, при одинаковом seed последовательность повторяется
+type Rand interface {
+	Float64() float64
+	Intn(n int) int
+}
+
+// lockedRand потокобезопасная обертка над rand.Rand
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+// New создает потокобезопасный генератор с заданным seed
+func New(seed int64) Rand {
+	return &lockedRand{r: rand.New(rand.NewSource(seed))}
+}
+
+// Derive создает генератор для отдельного потребителя, seed которого получен из общего seed и имени потребителя.
+// Последовательности потребителей не влияют друг на друга и воспроизводятся при одинаковом общем seed
+func Derive(seed int64, name string) Rand {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+
+	return New(seed ^ int64(h.Sum64()))
+}
+
+func (l *lockedRand) Float64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.r.Float64()
+}
+
+func (l *lockedRand) Intn(n int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.r.Intn(n)
+}
@@ -0,0 +1,22 @@
+package random
+
+import "testing"
+
+func TestDerive(t *testing.T) {
+	first, second := Derive(42, "orders"), Derive(42, "orders")
+	couriers := Derive(42, "couriers")
+
+	same, differs := true, false
+	for i := 0; i < 100; i++ {
+		a, b, c := first.Float64(), second.Float64(), couriers.Float64()
+		same = same && a == b
+		differs = differs || a != c
+	}
+
+	if !same {
+		t.Error("generators with the same seed and name differ")
+	}
+	if !differs {
+		t.Error("generators with different names are the same")
+	}
+}
@@ -1,16 +1,26 @@
 package router
 
 import (
+	ccontroller "github.com/GoGerman/geo-task/module/courier/controller"
 	"github.com/GoGerman/geo-task/module/courierfacade/controller"
+	lcontroller "github.com/GoGerman/geo-task/module/leaderboard/controller"
+	ledgercontroller "github.com/GoGerman/geo-task/module/ledger/controller"
+	scontroller "github.com/GoGerman/geo-task/module/shift/controller"
+	zcontroller "github.com/GoGerman/geo-task/module/zone/controller"
 	"github.com/gin-gonic/gin"
 )
 
 type Router struct {
-	courier *controller.CourierController
+	courier     *controller.CourierController
+	couriers    *ccontroller.CourierController
+	shifts      *scontroller.ShiftController
+	leaderboard *lcontroller.LeaderboardController
+	ledger      *ledgercontroller.LedgerController
+	zone        *zcontroller.ZoneController
 }
 
-func NewRouter(courier *controller.CourierController) *Router {
-	return &Router{courier: courier}
+func NewRouter(courier *controller.CourierController, couriers *ccontroller.CourierController, shifts *scontroller.ShiftController, leaderboard *lcontroller.LeaderboardController, ledger *ledgercontroller.LedgerController, zone *zcontroller.ZoneController) *Router {
+	return &Router{courier: courier, couriers: couriers, shifts: shifts, leaderboard: leaderboard, ledger: ledger, zone: zone}
 }
 
 func (r *Router) CourierAPI(router *gin.RouterGroup) {
@@ -18,6 +28,36 @@ func (r *Router) CourierAPI(router *gin.RouterGroup) {
 
 	router.GET("/status", r.courier.GetStatus)
 	router.GET("/ws", r.courier.Websocket)
+
+	router.GET("/couriers", r.couriers.List)
+	router.POST("/couriers", r.couriers.Create)
+	router.GET("/couriers/nearby", r.couriers.Nearby)
+	router.GET("/couriers/:id", r.couriers.Get)
+	router.DELETE("/couriers/:id", r.couriers.Delete)
+	router.GET("/couriers/:id/track", r.couriers.Track)
+	router.POST("/couriers/:id/state", r.couriers.SetState)
+	router.GET("/couriers/:id/states", r.couriers.States)
+
+	router.GET("/couriers/:id/shifts", r.shifts.List)
+	router.POST("/couriers/:id/shifts", r.shifts.Create)
+	router.GET("/couriers/:id/shifts/:shift_id", r.shifts.Get)
+	router.POST("/couriers/:id/shifts/:shift_id/start", r.shifts.Start)
+	router.POST("/couriers/:id/shifts/:shift_id/end", r.shifts.End)
+
+	router.GET("/couriers/:id/ledger", r.ledger.List)
+	router.POST("/couriers/:id/ledger", r.ledger.Create)
+	router.GET("/couriers/:id/balance", r.ledger.Balance)
+
+	router.GET("/leaderboard", r.leaderboard.Get)
+}
+
+func (r *Router) ZoneAPI(router *gin.RouterGroup) {
+	router.GET("/zones", r.zone.List)
+	router.GET("/zones.geojson", r.zone.GeoJSON)
+	router.POST("/zones", r.zone.Create)
+	router.GET("/zones/:id", r.zone.Get)
+	router.PUT("/zones/:id", r.zone.Update)
+	router.DELETE("/zones/:id", r.zone.Delete)
 }
 
 func (r *Router) Swagger(router *gin.RouterGroup) {
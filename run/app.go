@@ -3,22 +3,44 @@ package run
 import (
 	"context"
 	"github.com/GoGerman/geo-task/cache"
-	"github.com/GoGerman/geo-task/geo"
+	"github.com/GoGerman/geo-task/clock"
+	ccontroller "github.com/GoGerman/geo-task/module/courier/controller"
+	"github.com/GoGerman/geo-task/module/courier/events"
 	cservice "github.com/GoGerman/geo-task/module/courier/service"
 	storage2 "github.com/GoGerman/geo-task/module/courier/storage"
 	"github.com/GoGerman/geo-task/module/courierfacade/controller"
 	"github.com/GoGerman/geo-task/module/courierfacade/service"
+	lcontroller "github.com/GoGerman/geo-task/module/leaderboard/controller"
+	lservice "github.com/GoGerman/geo-task/module/leaderboard/service"
+	lstorage "github.com/GoGerman/geo-task/module/leaderboard/storage"
+	ledgercontroller "github.com/GoGerman/geo-task/module/ledger/controller"
+	ledgerservice "github.com/GoGerman/geo-task/module/ledger/service"
+	ledgerstorage "github.com/GoGerman/geo-task/module/ledger/storage"
 	oservice "github.com/GoGerman/geo-task/module/order/service"
 	"github.com/GoGerman/geo-task/module/order/storage"
+	scontroller "github.com/GoGerman/geo-task/module/shift/controller"
+	sservice "github.com/GoGerman/geo-task/module/shift/service"
+	sstorage "github.com/GoGerman/geo-task/module/shift/storage"
+	zcontroller "github.com/GoGerman/geo-task/module/zone/controller"
+	zservice "github.com/GoGerman/geo-task/module/zone/service"
+	zstorage "github.com/GoGerman/geo-task/module/zone/storage"
+	"github.com/GoGerman/geo-task/random"
 	"github.com/GoGerman/geo-task/router"
 	"github.com/GoGerman/geo-task/server"
+	"github.com/GoGerman/geo-task/workers/courier"
 	"github.com/GoGerman/geo-task/workers/order"
+	"github.com/GoGerman/geo-task/workers/zone"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 )
 
+const defaultZonesFile = "zones/spb.geojson"
+
 type App struct {
 }
 
@@ -44,15 +66,47 @@ func (a *App) Run() error {
 		return err
 	}
 
-	// инициализация разрешенной зоны
-	allowedZone := geo.NewAllowedZone()
-	// инициализация запрещенных зон
-	disAllowedZones := []geo.PolygonChecker{geo.NewDisAllowedZone1(), geo.NewDisAllowedZone2()}
+	// инициализация часов и seed генераторов случайных чисел
+	// при заданных SIM_SEED, SIM_START и SIM_STEP заказы воспроизводятся между запусками вместе с id и временем создания
+	seed, err := simulationSeed()
+	if err != nil {
+		return err
+	}
+	log.Printf("simulation seed: %d", seed)
+
+	clk, replay, err := simulationClock()
+	if err != nil {
+		return err
+	}
+
+	// зоны хранятся в redis, при первом запуске они загружаются из GeoJSON файла
+	zonesFile := os.Getenv("ZONES_FILE")
+	if zonesFile == "" {
+		zonesFile = defaultZonesFile
+	}
+
+	// инициализация хранилища зон
+	zoneStorage := zstorage.NewZoneStorage(rclient)
+	// инициализация сервиса зон
+	zoneService := zservice.NewZoneService(zoneStorage, clk)
+	err = zoneService.Init(context.Background(), zonesFile)
+	if err != nil {
+		return err
+	}
+
+	// при изменении зон на любом инстансе набор зон перезагружается без перезапуска
+	zoneReloader := zone.NewZoneReloader(zoneService, zoneStorage)
+	zoneReloader.Run()
 
 	// инициализация хранилища заказов
-	orderStorage := storage.NewOrderStorage(rclient)
-	// инициализация сервиса заказов
-	orderService := oservice.NewOrderService(orderStorage, allowedZone, disAllowedZones)
+	orderStorage := storage.NewOrderStorage(rclient, clk)
+	// инициализация сервиса заказов, у сервиса свой генератор, чтобы заказы не зависели от перемещений курьеров
+	// при воспроизведении id заказов идут с 1, а не продолжают счетчик прошлых запусков в redis
+	var orderIDs oservice.IDSource = orderStorage
+	if replay {
+		orderIDs = oservice.NewSequence()
+	}
+	orderService := oservice.NewOrderService(orderStorage, zoneService, clk, random.Derive(seed, "orders"), orderIDs)
 
 	orderGenerator := order.NewOrderGenerator(orderService)
 	orderGenerator.Run()
@@ -62,23 +116,79 @@ func (a *App) Run() error {
 
 	// инициализация хранилища курьеров
 	courierStorage := storage2.NewCourierStorage(rclient)
+	// инициализация хранилища треков курьеров
+	trackStorage := storage2.NewTrackStorage(rclient)
+	// инициализация хранилища присутствия курьеров
+	presenceStorage := storage2.NewPresenceStorage(rclient)
 	// инициализация сервиса курьеров
-	courierSevice := cservice.NewCourierService(courierStorage, allowedZone, disAllowedZones)
+	maxSpeed, err := courierMaxSpeed()
+	if err != nil {
+		return err
+	}
+	presenceTTL, err := courierPresenceTTL()
+	if err != nil {
+		return err
+	}
+	courierSevice := cservice.NewCourierService(courierStorage, trackStorage, presenceStorage, zoneService, newGeofenceSink(rclient), events.NewRedisSuspiciousSink(rclient), clk, random.Derive(seed, "couriers"), maxSpeed, presenceTTL)
+
+	// курьеры без heartbeat уходят offline и пропадают из гео индекса
+	presenceChecker := courier.NewPresenceChecker(courierSevice)
+	presenceChecker.Run()
+
+	// инициализация хранилища смен
+	shiftStorage := sstorage.NewShiftStorage(rclient)
+	// инициализация сервиса смен, вне смены курьер не получает заказы и не перемещается
+	shiftService := sservice.NewShiftService(shiftStorage, courierSevice, zoneService, clk)
+
+	// инициализация таблиц лидеров, сутки и неделя считаются в часовом поясе LEADERBOARD_TZ
+	location, err := leaderboardLocation()
+	if err != nil {
+		return err
+	}
+	leaderboardService := lservice.NewLeaderboardService(lstorage.NewLeaderboardStorage(rclient), courierSevice, clk, location)
+	err = leaderboardService.Init(context.Background())
+	if err != nil {
+		return err
+	}
+
+	// инициализация журнала начислений курьеров, суммы хранятся в копейках
+	ledgerService := ledgerservice.NewLedgerService(ledgerstorage.NewLedgerStorage(rclient), courierSevice, clk)
+
+	pickupDistance, err := courierPickupDistance()
+	if err != nil {
+		return err
+	}
 
 	// инициализация фасада сервиса курьеров
-	courierFacade := service.NewCourierFacade(courierSevice, orderService)
+	courierFacade := service.NewCourierFacade(courierSevice, orderService, shiftService, leaderboardService, ledgerService, pickupDistance)
 
 	// инициализация контроллера курьеров
 	courierController := controller.NewCourierController(courierFacade)
 
+	// инициализация контроллера управления курьерами
+	couriersController := ccontroller.NewCourierController(courierSevice)
+
+	// инициализация контроллера смен
+	shiftController := scontroller.NewShiftController(shiftService)
+
+	// инициализация контроллера таблиц лидеров
+	leaderboardController := lcontroller.NewLeaderboardController(leaderboardService)
+
+	// инициализация контроллера журнала начислений
+	ledgerController := ledgercontroller.NewLedgerController(ledgerService)
+
+	// инициализация контроллера зон
+	zoneController := zcontroller.NewZoneController(zoneService)
+
 	// инициализация роутера
-	routes := router.NewRouter(courierController)
+	routes := router.NewRouter(courierController, couriersController, shiftController, leaderboardController, ledgerController, zoneController)
 	// инициализация сервера
 	r := server.NewHTTPServer()
 	// инициализация группы роутов
 	api := r.Group("/api")
 	// инициализация роутов
 	routes.CourierAPI(api)
+	routes.ZoneAPI(api)
 
 	mainRoute := r.Group("/")
 
@@ -97,3 +207,86 @@ func (a *App) Run() error {
 
 	return r.Run()
 }
+
+// simulationSeed возвращает seed из переменной окружения SIM_SEED, если она не задана - seed по текущему времени
+func simulationSeed() (int64, error) {
+	if v := os.Getenv("SIM_SEED"); v != "" {
+		return strconv.ParseInt(v, 10, 64)
+	}
+
+	return time.Now().UnixNano(), nil
+}
+
+// simulationClock возвращает часы симуляции: при заданной переменной окружения SIM_START в формате RFC 3339
+// время начинается с нее и идет с обычной скоростью, иначе используются системные часы.
+// При заданном вместе с ней SIM_STEP (например 100ms) время сдвигается на шаг при каждом чтении и не зависит
+// от скорости выполнения, такой запуск - воспроизведение, replay = true
+func simulationClock() (clk clock.Clock, replay bool, err error) {
+	v := os.Getenv("SIM_START")
+	if v == "" {
+		return clock.Real{}, false, nil
+	}
+
+	start, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if v := os.Getenv("SIM_STEP"); v != "" {
+		step, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return clock.NewStepped(start, step), true, nil
+	}
+
+	return clock.NewOffset(start), false, nil
+}
+
+// courierPickupDistance возвращает расстояние в метрах, с которого курьер забирает заказ, из переменной окружения PICKUP_DISTANCE
+func courierPickupDistance() (float64, error) {
+	if v := os.Getenv("PICKUP_DISTANCE"); v != "" {
+		return strconv.ParseFloat(v, 64)
+	}
+
+	return service.DefaultPickupDistance, nil
+}
+
+// courierMaxSpeed возвращает максимальную скорость курьера в м/с из переменной окружения COURIER_MAX_SPEED
+func courierMaxSpeed() (float64, error) {
+	if v := os.Getenv("COURIER_MAX_SPEED"); v != "" {
+		return strconv.ParseFloat(v, 64)
+	}
+
+	return cservice.DefaultMaxSpeed, nil
+}
+
+// courierPresenceTTL возвращает время, через которое курьер без heartbeat уходит offline,
+// из переменной окружения COURIER_PRESENCE_TTL в формате time.ParseDuration, например 90s
+func courierPresenceTTL() (time.Duration, error) {
+	if v := os.Getenv("COURIER_PRESENCE_TTL"); v != "" {
+		return time.ParseDuration(v)
+	}
+
+	return cservice.DefaultPresenceTTL, nil
+}
+
+// leaderboardLocation возвращает часовой пояс таблиц лидеров из переменной окружения LEADERBOARD_TZ, по умолчанию локальный
+func leaderboardLocation() (*time.Location, error) {
+	if v := os.Getenv("LEADERBOARD_TZ"); v != "" {
+		return time.LoadLocation(v)
+	}
+
+	return time.Local, nil
+}
+
+// newGeofenceSink выбирает получателя событий геозон по переменным окружения:
+// GEOFENCE_WEBHOOK_URL - отправка вебхуком в фоне, иначе события пишутся в redis stream
+func newGeofenceSink(rclient *redis.Client) events.GeofenceSink {
+	if url := os.Getenv("GEOFENCE_WEBHOOK_URL"); url != "" {
+		return events.NewAsyncSink(events.NewWebhookSink(url), events.DefaultAsyncSinkSize)
+	}
+
+	return events.NewRedisStreamSink(rclient)
+}
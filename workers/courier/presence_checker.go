@@ -0,0 +1,47 @@
+package courier
+
+import (
+	"context"
+	"github.com/GoGerman/geo-task/module/courier/service"
+	"log"
+	"time"
+)
+
+const (
+	presenceCheckInterval = 10 * time.Second
+)
+
+// PresenceChecker воркер, который переводит offline курьеров, от которых давно не было heartbeat,
+// например если вкладка браузера закрылась без закрытия WebSocket
+type PresenceChecker struct {
+	courierService service.Courierer
+}
+
+func NewPresenceChecker(courierService service.Courierer) *PresenceChecker {
+	return &PresenceChecker{courierService: courierService}
+}
+
+func (p *PresenceChecker) check(ctx context.Context) {
+	ticker := time.NewTicker(presenceCheckInterval)
+	for {
+		select {
+		case <-ctx.Done():
+			ticker.Stop()
+			return
+		case <-ticker.C:
+			expired, err := p.courierService.ExpirePresence(ctx)
+			if err != nil {
+				log.Printf("error while expiring courier presence: %v", err)
+			}
+
+			if expired > 0 {
+				log.Printf("couriers went offline without heartbeat: %d", expired)
+			}
+		}
+	}
+}
+
+func (p *PresenceChecker) Run() {
+	ctx := context.Background()
+	go p.check(ctx)
+}
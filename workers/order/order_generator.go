@@ -2,6 +2,7 @@ package order
 
 import (
 	"context"
+	"errors"
 	"github.com/GoGerman/geo-task/module/order/service"
 	"log"
 	"time"
@@ -45,7 +46,8 @@ func (o *OrderGenerator) orderCreater(ctx context.Context) {
 			}
 
 			err = o.orderService.GenerateOrder(ctx)
-			if err != nil {
+			// лимит заказов в зонах освободится, когда заказы заберут или они устареют
+			if err != nil && !errors.Is(err, service.ErrOrderLimitReached) {
 				log.Printf("error while generating order: %v", err)
 			}
 		}
@@ -0,0 +1,58 @@
+package zone
+
+import (
+	"context"
+	"github.com/GoGerman/geo-task/module/zone/service"
+	"github.com/GoGerman/geo-task/module/zone/storage"
+	"log"
+	"time"
+)
+
+const (
+	// интервал повторной подписки при потере соединения с redis
+	resubscribeInterval = 5 * time.Second
+)
+
+// ZoneReloader воркер, который перезагружает зоны при их изменении на любом инстансе
+type ZoneReloader struct {
+	zoneService service.Zoner
+	zoneStorage storage.ZoneStorager
+}
+
+func NewZoneReloader(zoneService service.Zoner, zoneStorage storage.ZoneStorager) *ZoneReloader {
+	return &ZoneReloader{zoneService: zoneService, zoneStorage: zoneStorage}
+}
+
+func (z *ZoneReloader) zoneWatcher(ctx context.Context) {
+	for {
+		updates, err := z.zoneStorage.SubscribeUpdated(ctx)
+		if err != nil {
+			log.Printf("error while subscribing to zone updates: %v", err)
+		} else {
+			// после переподписки зоны перечитываются, так как изменения могли быть пропущены
+			z.reload(ctx)
+
+			for range updates {
+				z.reload(ctx)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(resubscribeInterval):
+		}
+	}
+}
+
+func (z *ZoneReloader) reload(ctx context.Context) {
+	err := z.zoneService.Reload(ctx)
+	if err != nil {
+		log.Printf("error while reloading zones: %v", err)
+	}
+}
+
+func (z *ZoneReloader) Run() {
+	ctx := context.Background()
+	go z.zoneWatcher(ctx)
+}